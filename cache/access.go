@@ -0,0 +1,75 @@
+package gocachex
+
+import (
+	"sort"
+	"time"
+)
+
+// KeyStat 是一个缓存项的访问统计
+type KeyStat struct {
+	Key        string    // 缓存键
+	Count      int64     // 写入缓存以来的命中次数
+	LastAccess time.Time // 最近一次命中的时间，从未命中时为写入时间
+}
+
+// accessStats 记录当前在缓存中的缓存项的访问统计，缓存项被淘汰时一并删除
+type accessStats struct {
+	keys map[string]*KeyStat
+}
+
+func newAccessStats() *accessStats {
+	return &accessStats{keys: make(map[string]*KeyStat)}
+}
+
+// added 在缓存项写入时建立统计，覆盖已有缓存项时保留原有计数
+func (a *accessStats) added(key string) {
+	if _, ok := a.keys[key]; !ok {
+		a.keys[key] = &KeyStat{Key: key, LastAccess: time.Now()}
+	}
+}
+
+// hit 记录一次命中
+func (a *accessStats) hit(key string) {
+	if st, ok := a.keys[key]; ok {
+		st.Count++
+		st.LastAccess = time.Now()
+	}
+}
+
+// WithAccessStats 开启按键的访问统计，配合Group.AccessStats找出最常访问的键，
+// 用于决定过期时间或需要常驻的数据。统计只覆盖当前在缓存中的缓存项，随淘汰一并删除，
+// 因此内存占用有上限；开销是每个缓存项额外约80字节，以及每次命中多一次map查找和一次time.Now
+func WithAccessStats() GroupOption {
+	return func(g *Group) {
+		g.mainCache.access = newAccessStats()
+	}
+}
+
+// topAccessed 返回命中次数最多的n个缓存项
+func (c *cache) topAccessed(n int) []KeyStat {
+	c.lock()
+	defer c.unlock()
+	if c.access == nil || n <= 0 {
+		return nil
+	}
+	stats := make([]KeyStat, 0, len(c.access.keys))
+	for _, st := range c.access.keys {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// AccessStats 返回本地缓存中命中次数最多的topN个键，按次数从多到少排列，次数相同时按键排序
+// 需要通过WithAccessStats开启统计，否则返回nil。只统计Get等对外读取接口的命中
+func (g *Group) AccessStats(topN int) []KeyStat {
+	return g.mainCache.topAccessed(topN)
+}
@@ -0,0 +1,111 @@
+package gocachex
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+)
+
+// ByteViewBatch 将多个键值对依次打包在一块连续的缓冲区中，并维护一个偏移量索引
+// 与map[string][]byte相比，无论包含多少条目都只有缓冲区和索引两次分配，
+// 适合节点一次返回几十个值的批量响应。缓冲区本身就是编码格式：
+// 每个条目依次为 uvarint(len(key)) key uvarint(len(value)) value，
+// 因此Bytes无需复制即可发送，解码时也只需建立索引而不复制数据
+type ByteViewBatch struct {
+	buf   []byte       // 所有条目的编码数据，已写入的部分不再修改
+	index []batchEntry // 每个条目的键和值在buf中的位置
+}
+
+// batchEntry 记录一个条目的键和值在缓冲区中的位置
+type batchEntry struct {
+	keyOff, keyLen int
+	valOff, valLen int
+}
+
+// errBadBatch 表示批量数据格式错误
+var errBadBatch = errors.New("gocachex: malformed batch")
+
+// Add 追加一个键值对，value会被复制到缓冲区中
+func (b *ByteViewBatch) Add(key string, value []byte) {
+	var e batchEntry
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(key)))
+	e.keyOff, e.keyLen = len(b.buf), len(key)
+	b.buf = append(b.buf, key...)
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(value)))
+	e.valOff, e.valLen = len(b.buf), len(value)
+	b.buf = append(b.buf, value...)
+	b.index = append(b.index, e)
+}
+
+// Len 返回条目数量
+func (b *ByteViewBatch) Len() int {
+	return len(b.index)
+}
+
+// Key 返回第i个条目的键，不复制数据
+func (b *ByteViewBatch) Key(i int) string {
+	e := b.index[i]
+	if e.keyLen == 0 {
+		return ""
+	}
+	// 缓冲区已写入的部分不会再被修改，可以安全地直接引用
+	return unsafe.String(&b.buf[e.keyOff], e.keyLen)
+}
+
+// Value 返回第i个条目的值，不复制数据
+func (b *ByteViewBatch) Value(i int) ByteView {
+	e := b.index[i]
+	return ByteView{b: b.buf[e.valOff : e.valOff+e.valLen : e.valOff+e.valLen]}
+}
+
+// Range 按添加顺序遍历所有条目，fn返回false时停止，遍历过程中不复制数据
+func (b *ByteViewBatch) Range(fn func(key string, value ByteView) bool) {
+	for i := range b.index {
+		if !fn(b.Key(i), b.Value(i)) {
+			return
+		}
+	}
+}
+
+// Get 查找键对应的值，条目较少时线性查找比建立map更快
+func (b *ByteViewBatch) Get(key string) (ByteView, bool) {
+	for i := range b.index {
+		if b.Key(i) == key {
+			return b.Value(i), true
+		}
+	}
+	return ByteView{}, false
+}
+
+// Bytes 返回批量数据的编码结果，直接返回内部缓冲区，调用方不能修改
+func (b *ByteViewBatch) Bytes() []byte {
+	return b.buf
+}
+
+// DecodeByteViewBatch 解析Bytes编码的批量数据，只建立索引而不复制data，
+// 返回的批量数据引用data，调用方此后不能再修改data
+func DecodeByteViewBatch(data []byte) (*ByteViewBatch, error) {
+	b := &ByteViewBatch{buf: data}
+	for off := 0; off < len(data); {
+		var e batchEntry
+		var err error
+		if e.keyOff, e.keyLen, err = readChunk(data, off); err != nil {
+			return nil, err
+		}
+		if e.valOff, e.valLen, err = readChunk(data, e.keyOff+e.keyLen); err != nil {
+			return nil, err
+		}
+		b.index = append(b.index, e)
+		off = e.valOff + e.valLen
+	}
+	return b, nil
+}
+
+// readChunk 从off处读取一个长度前缀，返回数据的起始位置和长度
+func readChunk(data []byte, off int) (start, n int, err error) {
+	l, size := binary.Uvarint(data[off:])
+	if size <= 0 || l > uint64(len(data)-off-size) {
+		return 0, 0, errBadBatch
+	}
+	return off + size, int(l), nil
+}
@@ -0,0 +1,79 @@
+package gocachex
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestByteViewBatchRoundTrip(t *testing.T) {
+	var batch ByteViewBatch
+	want := map[string]string{"Tom": "630", "": "empty-key", "empty-value": "", "Jack": "589"}
+	order := []string{"Tom", "", "empty-value", "Jack"}
+	for _, k := range order {
+		batch.Add(k, []byte(want[k]))
+	}
+
+	decoded, err := DecodeByteViewBatch(batch.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Len() != len(order) {
+		t.Fatalf("expect %d entries, got %d", len(order), decoded.Len())
+	}
+	i := 0
+	decoded.Range(func(key string, value ByteView) bool {
+		if key != order[i] || value.String() != want[key] {
+			t.Errorf("entry %d: expect %q=%q, got %q=%q", i, order[i], want[order[i]], key, value.String())
+		}
+		i++
+		return true
+	})
+	if v, ok := decoded.Get("Jack"); !ok || v.String() != "589" {
+		t.Errorf("expect Jack=589, got %q", v.String())
+	}
+	if _, ok := decoded.Get("Sam"); ok {
+		t.Error("Sam should not be found")
+	}
+
+	for _, bad := range [][]byte{{0x05, 'a'}, {0x01, 'a', 0x09}, {0xff}} {
+		if _, err := DecodeByteViewBatch(bad); err == nil {
+			t.Errorf("expect error for malformed batch %v", bad)
+		}
+	}
+}
+
+func benchmarkValues() (keys []string, values [][]byte) {
+	for i := 0; i < 50; i++ {
+		keys = append(keys, fmt.Sprintf("key%d", i))
+		values = append(values, make([]byte, 128))
+	}
+	return
+}
+
+func BenchmarkByteViewBatch(b *testing.B) {
+	keys, values := benchmarkValues()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		batch := ByteViewBatch{
+			buf:   make([]byte, 0, 8<<10),
+			index: make([]batchEntry, 0, len(keys)),
+		}
+		for j, k := range keys {
+			batch.Add(k, values[j])
+		}
+		batch.Range(func(key string, value ByteView) bool { return true })
+	}
+}
+
+func BenchmarkByteMap(b *testing.B) {
+	keys, values := benchmarkValues()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := make(map[string][]byte, len(keys))
+		for j, k := range keys {
+			m[k] = cloneBytes(values[j])
+		}
+		for range m {
+		}
+	}
+}
@@ -6,6 +6,13 @@ tip:
 */
 package gocachex
 
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
 // ByteView 是一个只读的数据结构，用于表示缓存值
 // 它封装了 []byte 类型，实现了 Value 接口
 // 所有返回的数据均为原始数据的副本，确保安全性
@@ -42,3 +49,35 @@ func cloneBytes(b []byte) []byte {
 func (v ByteView) String() string {
 	return string(v.b)
 }
+
+// Reader 返回一个读取底层数据的只读io.ReadSeeker
+// 不会复制数据，适合读取或按范围发送较大的值
+func (v ByteView) Reader() *bytes.Reader {
+	return bytes.NewReader(v.b)
+}
+
+// Version 返回值内容的版本标识，格式为带引号的HTTP ETag
+// 版本由内容的FNV-64a哈希得出，内容相同的值版本一定相同
+func (v ByteView) Version() string {
+	h := fnv.New64a()
+	h.Write(v.b)
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// bufPool 缓存被淘汰的值所占用的字节切片，供后续加载复用，减少GC压力
+// 仅在Group开启WithBufferPool时使用
+var bufPool sync.Pool
+
+// getBuffer 从缓冲池中取出一个长度为n的字节切片，容量不足时重新分配
+func getBuffer(n int) []byte {
+	if bp, ok := bufPool.Get().(*[]byte); ok && cap(*bp) >= n {
+		return (*bp)[:n]
+	}
+	return make([]byte, n)
+}
+
+// putBuffer 将不再被引用的字节切片放回缓冲池
+func putBuffer(b []byte) {
+	b = b[:0]
+	bufPool.Put(&b)
+}
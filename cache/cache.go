@@ -4,15 +4,147 @@ package gocachex
 
 import (
 	"goCacheX/lru"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// byteViewLRU 是值类型为ByteView的LRU缓存，读取时不需要类型断言
+type byteViewLRU = lru.Cache[ByteView]
+
 // cache 是对LRU缓存的并发安全封装
 // 内部使用互斥锁实现并发控制，保证在多线程环境下安全访问缓存
 type cache struct {
-	mu         sync.Mutex // 互斥锁，用于保证缓存操作的原子性
-	lru        *lru.Cache // LRU缓存实例，存储实际的缓存数据
-	cacheBytes int64      // 缓存的最大内存限制（字节）
+	mu         sync.Mutex   // 互斥锁，用于保证缓存操作的原子性
+	lru        *byteViewLRU // LRU缓存实例，存储实际的缓存数据
+	cacheBytes int64        // 缓存的最大内存限制（字节）
+
+	onEvicted func(key string, value ByteView) // 可选，缓存项被淘汰时调用
+	evictions int64                            // 被淘汰的缓存项数量
+
+	pending chan admission // 可选的写入缓冲区，批量写入LRU以减少锁竞争
+
+	dedup  map[uint64]*blob // 可选，按内容哈希索引的共享值，为nil时不去重
+	index  *valueIndex      // 可选，按值的属性查找键的二级索引
+	tags   *valueIndex      // 标签到缓存键的索引，第一次SetWithTags时创建
+	access *accessStats     // 可选，缓存项的访问统计
+
+	tenants *tenantQuotas // 可选，按租户的内存配额
+
+	pins map[*byte]*bufferPin // 开启缓冲池时，正在被GetReader读取的值的内存
+
+	policy lru.Policy // 可选，MigratePolicy之后缓存项写入这里，lru中只剩尚未迁移的缓存项
+
+	evictedKeys *lru.Cache[lru.Value] // 最近因容量不足被淘汰的键，用于区分容量未命中和冷未命中
+
+	loading map[string]*loadGen // 正在由getter加载的键，删除缓存项时递增代数，使加载结果不再写入
+
+	trackLock bool         // 是否记录加锁时间，供锁看门狗检查
+	lockedAt  atomic.Int64 // 当前持有c.mu的起始时间（UnixNano），0表示未持有
+}
+
+// lock 获取c.mu，开启锁看门狗时记录加锁时间
+func (c *cache) lock() {
+	c.mu.Lock()
+	if c.trackLock {
+		c.lockedAt.Store(time.Now().UnixNano())
+	}
+}
+
+// unlock 释放c.mu
+func (c *cache) unlock() {
+	if c.trackLock {
+		c.lockedAt.Store(0)
+	}
+	c.mu.Unlock()
+}
+
+// heldFor 返回当前持有c.mu的时长，未持有时返回0
+func (c *cache) heldFor() time.Duration {
+	at := c.lockedAt.Load()
+	if at == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, at))
+}
+
+// maxEvictedKeys 是记录最近被淘汰的键的数量上限，只保存键不保存值
+const maxEvictedKeys = 4096
+
+// evictedKey 是evictedKeys中的一条记录，不占用值的字节数
+type evictedKey struct{}
+
+// Len 实现lru.Value接口
+func (evictedKey) Len() int { return 0 }
+
+// noteEvicted 记录因容量不足被淘汰的键，调用方需持有c.mu
+func (c *cache) noteEvicted(key string) {
+	if c.evictedKeys == nil {
+		c.evictedKeys = lru.NewWithLimits(0, maxEvictedKeys, nil)
+	}
+	c.evictedKeys.Add(key, evictedKey{})
+}
+
+// recentlyEvicted 判断key是否在最近因容量不足被淘汰的键中
+func (c *cache) recentlyEvicted(key string) bool {
+	c.lock()
+	defer c.unlock()
+	if c.evictedKeys == nil {
+		return false
+	}
+	_, ok := c.evictedKeys.Peek(key)
+	return ok
+}
+
+// admission 是一条等待写入LRU的缓存项
+type admission struct {
+	key    string
+	value  ByteView
+	ticket loadTicket // 写入来自getter加载时，用于丢弃加载期间被删除的键的结果
+}
+
+// loadGen 是一个键的加载代数，该键进行中的所有加载共享
+type loadGen struct {
+	gen  uint64 // 加载期间键被删除的次数
+	refs int    // 进行中的加载数量，为0时从cache.loading中删除
+}
+
+// loadTicket 记录一次加载开始时键的代数，零值表示写入不来自加载，总是有效
+type loadTicket struct {
+	lg  *loadGen
+	gen uint64
+}
+
+// staleLocked 返回加载开始后键是否被删除过，调用方需持有c.mu
+func (t loadTicket) staleLocked() bool {
+	return t.lg != nil && t.lg.gen != t.gen
+}
+
+// loadStart 在getter加载开始前记录键的代数，加载结束后必须调用loadEnd
+func (c *cache) loadStart(key string) loadTicket {
+	c.lock()
+	defer c.unlock()
+	if c.loading == nil {
+		c.loading = make(map[string]*loadGen)
+	}
+	lg := c.loading[key]
+	if lg == nil {
+		lg = &loadGen{}
+		c.loading[key] = lg
+	}
+	lg.refs++
+	return loadTicket{lg: lg, gen: lg.gen}
+}
+
+// loadEnd 结束loadStart开始的加载
+// 结果可能仍在写入缓冲区中，此后删除该键时会先写入缓冲区再删除，因此不再需要记录代数
+func (c *cache) loadEnd(key string, t loadTicket) {
+	c.lock()
+	defer c.unlock()
+	if t.lg.refs--; t.lg.refs == 0 && c.loading[key] == t.lg {
+		delete(c.loading, key)
+	}
 }
 
 // add 添加一个键值对到缓存
@@ -21,12 +153,151 @@ type cache struct {
 //   - key: 缓存键
 //   - value: 缓存值，为只读的ByteView类型
 func (c *cache) add(key string, value ByteView) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.lru == nil { // 延迟初始化
-		c.lru = lru.New(c.cacheBytes, nil)
+	c.lock()
+	defer c.unlock()
+	c.addLocked(key, value)
+}
+
+// addWithTTL 与add相同，缓存项在ttl后过期，ttl不大于0表示永不过期
+// 先写入缓冲区中等待的缓存项，避免它们随后覆盖本次写入
+func (c *cache) addWithTTL(key string, value ByteView, ttl time.Duration) {
+	c.addLoaded(key, value, ttl, loadTicket{})
+}
+
+// addLoaded 与addWithTTL相同，ticket对应的加载开始后键被删除过时丢弃本次写入
+func (c *cache) addLoaded(key string, value ByteView, ttl time.Duration, ticket loadTicket) {
+	c.lock()
+	defer c.unlock()
+	c.drainLocked()
+	if ticket.staleLocked() {
+		return
+	}
+	c.addLockedTTL(key, value, ttl)
+}
+
+// migrated 返回是否已经迁移到新的淘汰策略
+func (c *cache) migrated() bool {
+	c.lock()
+	defer c.unlock()
+	return c.policy != nil
+}
+
+// addLocked 添加一个键值对到缓存，调用方需持有c.mu
+func (c *cache) addLocked(key string, value ByteView) {
+	c.addLockedTTL(key, value, 0)
+}
+
+// addLockedTTL 与addLocked相同，ttl大于0时缓存项在ttl后过期
+// 迁移后的淘汰策略不支持过期时间，调用方需要事先检查
+func (c *cache) addLockedTTL(key string, value ByteView, ttl time.Duration) {
+	c.initLocked()
+	if c.tags != nil {
+		// 覆盖写入不会触发淘汰回调，新值不再带有旧值的标签
+		c.tags.remove(key)
+	}
+	if c.evictedKeys != nil {
+		c.evictedKeys.Delete(key)
+	}
+	if c.policy != nil {
+		// 迁移开始后只写入新策略，LRU中的旧值不再迁移，避免覆盖新值
+		c.lru.Take(key)
+		c.policy.Add(key, value)
+		return
+	}
+	if c.dedup != nil {
+		// 覆盖已有的值不会触发淘汰回调，需要在这里释放旧值的引用
+		// 使用Peek读取旧值，覆盖写入不计为一次命中，也不改变访问顺序
+		if old, ok := c.lru.Peek(key); ok {
+			c.release(old)
+		}
+		value = c.intern(value)
+	}
+	if c.index != nil {
+		// 在写入LRU之前建立索引，值过大被立即淘汰时索引也会随之删除
+		c.index.add(key, value)
+	}
+	if c.access != nil {
+		c.access.added(key)
+	}
+	c.lru.AddWithTTL(key, value, ttl)
+	if c.tenants != nil {
+		// 值过大被立即淘汰时不再记录
+		if _, ok := c.lru.Peek(key); ok {
+			c.tenants.added(key, value.Len())
+			c.enforceTenantQuota(key)
+		}
+	}
+}
+
+// initLocked 延迟初始化LRU，调用方需持有c.mu
+func (c *cache) initLocked() {
+	if c.lru != nil {
+		return
+	}
+	c.lru = lru.NewWithSizer(c.cacheBytes, ByteView.Len, c.evictedLocked)
+	c.lru.OnEvictedReason = func(key string, value ByteView, reason lru.EvictReason) {
+		if reason == lru.EvictCapacity {
+			c.noteEvicted(key)
+		}
+	}
+}
+
+// evictedLocked 是缓存项离开LRU或迁移后的淘汰策略时的回调，回调在持有c.mu时执行
+func (c *cache) evictedLocked(key string, value ByteView) {
+	c.evictions++
+	if c.dedup != nil {
+		c.release(value)
+	}
+	if c.index != nil {
+		c.index.remove(key)
+	}
+	if c.tags != nil {
+		c.tags.remove(key)
+	}
+	if c.access != nil {
+		delete(c.access.keys, key)
+	}
+	if c.tenants != nil {
+		c.tenants.removed(key)
+	}
+	if c.onEvicted != nil {
+		c.onEvicted(key, value)
+	}
+}
+
+// admit 写入一个缓存项，开启写入缓冲区时先放入缓冲区
+// 缓冲区满时由当前调用方在一次加锁中把缓冲区内的所有缓存项批量写入LRU
+func (c *cache) admit(key string, value ByteView, ticket loadTicket) {
+	if c.pending == nil {
+		c.addLoaded(key, value, 0, ticket)
+		return
+	}
+	select {
+	case c.pending <- admission{key, value, ticket}:
+	default:
+		c.drain(&admission{key, value, ticket})
+	}
+}
+
+// drain 在一次加锁中将缓冲区中的缓存项（以及extra）全部写入LRU
+func (c *cache) drain(extra *admission) {
+	c.lock()
+	defer c.unlock()
+	c.drainLocked()
+	if extra != nil && !extra.ticket.staleLocked() {
+		c.addLocked(extra.key, extra.value)
+	}
+}
+
+// drainLocked 将缓冲区中的缓存项全部写入LRU，调用方需持有c.mu
+func (c *cache) drainLocked() {
+	// 只有持有c.mu的调用方会从缓冲区读取，len大于0时读取不会阻塞
+	for len(c.pending) > 0 {
+		a := <-c.pending
+		if !a.ticket.staleLocked() {
+			c.addLocked(a.key, a.value)
+		}
 	}
-	c.lru.Add(key, value)
 }
 
 // get 根据键获取缓存值
@@ -35,16 +306,234 @@ func (c *cache) add(key string, value ByteView) {
 //   - ByteView: 缓存的值，如果键不存在返回空ByteView
 //   - bool: 表示键是否存在于缓存中
 func (c *cache) get(key string) (value ByteView, ok bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.getCounted(key, false)
+}
+
+// getCounted 与get相同，count为true且开启访问统计时记录一次访问
+func (c *cache) getCounted(key string, count bool) (value ByteView, ok bool) {
+	c.lock()
+	defer c.unlock()
+	v, ok := c.lookupLocked(key)
+	if !ok && len(c.pending) > 0 {
+		// 开启写入缓冲区时，未命中后把缓冲区写入LRU再查一次，使刚加载的缓存项对后续请求可见；
+		// 命中时不处理缓冲区，只加一次锁
+		c.drainLocked()
+		v, ok = c.lookupLocked(key)
+	}
+	if ok {
+		if count && c.access != nil {
+			c.access.hit(key)
+		}
+		if c.tenants != nil {
+			c.tenants.touch(key)
+		}
+		return v, true
+	}
+	return
+}
+
+// lookupLocked 从LRU查询key，调用方需持有c.mu
+// 迁移淘汰策略期间先查新策略，再查尚未迁移的LRU
+func (c *cache) lookupLocked(key string) (ByteView, bool) {
+	if c.policy != nil {
+		if v, ok := c.policy.Get(key); ok {
+			return v.(ByteView), true
+		}
+	}
 	if c.lru == nil { // 这个判断有必要，避免还没有初始化缓存时，调用get方法
-		return
+		return ByteView{}, false
 	}
+	return c.lru.Get(key)
+}
 
-	if v, ok := c.lru.Get(key); ok {
-		return v.(ByteView), true
+// peek 根据键获取缓存值，不改变访问顺序
+func (c *cache) peek(key string) (value ByteView, ok bool) {
+	c.lock()
+	defer c.unlock()
+	return c.peekLocked(key)
+}
+
+// peekLocked 查询key而不改变访问顺序，迁移淘汰策略期间先查新策略，调用方需持有c.mu
+func (c *cache) peekLocked(key string) (ByteView, bool) {
+	if c.policy != nil {
+		if v, ok := c.policy.Peek(key); ok {
+			return v.(ByteView), true
+		}
 	}
-	return
+	if c.lru == nil {
+		return ByteView{}, false
+	}
+	return c.lru.Peek(key)
+}
+
+// evictOldest 同步淘汰最久未使用的缓存项，供全局内存上限使用，也便于测试中确定性地触发淘汰
+// 迁移淘汰策略期间先淘汰尚未迁移的LRU，LRU为空后由新策略选择淘汰的缓存项
+func (c *cache) evictOldest() {
+	c.lock()
+	defer c.unlock()
+	c.evictOldestLocked()
+}
+
+// evictOldestLocked 与evictOldest相同，调用方需持有c.mu
+func (c *cache) evictOldestLocked() {
+	if c.lru != nil && c.lru.Len() > 0 {
+		c.lru.RemoveOldest()
+	} else if c.policy != nil {
+		c.policy.RemoveOldest()
+	}
+}
+
+// evictBytes 在一次加锁中淘汰最旧的缓存项，直到释放了need字节或淘汰了max个，返回实际释放的字节数
+// 某次淘汰没有释放内存时（例如缓存已空，或迁移后的策略无法再淘汰）立即停止
+func (c *cache) evictBytes(need int64, max int) int64 {
+	c.lock()
+	defer c.unlock()
+	var freed int64
+	for i := 0; i < max && freed < need; i++ {
+		before := c.bytesLocked()
+		c.evictOldestLocked()
+		n := before - c.bytesLocked()
+		if n <= 0 {
+			break
+		}
+		freed += n
+	}
+	return freed
+}
+
+// oldestN 返回最久未使用的n个键（即最先被淘汰的候选），按从旧到新排列
+// 不会改变缓存的访问顺序
+// 迁移淘汰策略期间尚未迁移的LRU中的键在前，之后是新策略中最先被淘汰的键
+func (c *cache) oldestN(n int) []string {
+	c.lock()
+	defer c.unlock()
+	var keys []string
+	if c.lru != nil {
+		keys = c.lru.OldestN(n)
+	}
+	if c.policy != nil && len(keys) < n {
+		pk := c.policy.Keys()
+		for i := len(pk) - 1; i >= 0 && len(keys) < n; i-- {
+			keys = append(keys, pk[i])
+		}
+	}
+	return keys
+}
+
+// newestN 返回最近使用的n个键，按从新到旧排列，不会改变缓存的访问顺序
+// 迁移淘汰策略期间新策略中的键在前
+func (c *cache) newestN(n int) []string {
+	c.lock()
+	defer c.unlock()
+	var keys []string
+	if c.policy != nil {
+		keys = c.policy.Keys()
+		keys = keys[:min(n, len(keys))]
+	}
+	if c.lru != nil && len(keys) < n {
+		keys = append(keys, c.lru.NewestN(n-len(keys))...)
+	}
+	return keys
+}
+
+// newestMatching 按从新到旧的顺序返回最多n个满足match的键，不会改变缓存的访问顺序
+func (c *cache) newestMatching(n int, match func(key string) bool) []string {
+	c.lock()
+	defer c.unlock()
+	var keys []string
+	if c.policy != nil {
+		for _, key := range c.policy.Keys() {
+			if len(keys) == n {
+				return keys
+			}
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if c.lru == nil || len(keys) == n {
+		return keys
+	}
+	c.lru.Range(func(key string, _ ByteView) bool {
+		if match(key) {
+			keys = append(keys, key)
+		}
+		return len(keys) < n
+	})
+	return keys
+}
+
+// sampleKeys 随机返回最多n个缓存中的键
+func (c *cache) sampleKeys(n int) []string {
+	c.lock()
+	defer c.unlock()
+	var keys []string
+	if c.lru != nil {
+		keys = c.lru.SampleKeys(n)
+	}
+	if c.policy != nil && len(keys) < n {
+		// Policy不支持随机取样，打乱全部键后取出所需的数量
+		pk := c.policy.Keys()
+		rand.Shuffle(len(pk), func(i, j int) { pk[i], pk[j] = pk[j], pk[i] })
+		keys = append(keys, pk[:min(n-len(keys), len(pk))]...)
+	}
+	return keys
+}
+
+// keys 返回缓存中的所有键，不会改变缓存的访问顺序
+func (c *cache) keys() []string {
+	c.lock()
+	defer c.unlock()
+	var keys []string
+	if c.policy != nil {
+		keys = c.policy.Keys()
+	}
+	if c.lru != nil {
+		keys = append(keys, c.lru.Keys()...)
+	}
+	return keys
+}
+
+// removeIf 在一次加锁中删除keys中满足pred的缓存项，返回删除的数量
+// 删除会触发淘汰回调，与其他原因的淘汰一样计入evictions
+func (c *cache) removeIf(keys []string, pred func(key string) bool) int {
+	c.lock()
+	defer c.unlock()
+	// 先写入缓冲区中的缓存项，避免它们在删除之后才写入LRU
+	c.drainLocked()
+	n := 0
+	for _, key := range keys {
+		if pred(key) && c.removeLocked(key) {
+			n++
+		}
+	}
+	return n
+}
+
+// remove 删除一个缓存项，返回缓存项是否存在
+func (c *cache) remove(key string) bool {
+	c.lock()
+	defer c.unlock()
+	c.drainLocked()
+	return c.removeLocked(key)
+}
+
+// removeLocked 删除一个缓存项，返回缓存项是否存在，调用方需持有c.mu并已写入缓冲区中的缓存项
+// 键正在加载时递增它的代数，加载结果不会再写入缓存
+func (c *cache) removeLocked(key string) bool {
+	if lg := c.loading[key]; lg != nil {
+		lg.gen++
+	}
+	if c.lru != nil && c.lru.Delete(key) {
+		return true
+	}
+	if c.policy != nil && c.policy.Delete(key) {
+		if c.evictedKeys != nil {
+			c.evictedKeys.Delete(key) // 显式删除不算容量不足的淘汰
+		}
+		return true
+	}
+	return false
 }
 
 // Len 返回缓存中的元素数量
@@ -52,7 +541,89 @@ func (c *cache) get(key string) (value ByteView, ok bool) {
 // 返回:
 //   - int: 缓存中的元素数量
 func (c *cache) Len() int {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.lru.Len()
+	c.lock()
+	defer c.unlock()
+	n := 0
+	if c.lru != nil {
+		n = c.lru.Len()
+	}
+	if c.policy != nil {
+		n += c.policy.Len()
+	}
+	return n
+}
+
+// clear 丢弃缓存中的所有数据，释放LRU占用的内存
+func (c *cache) clear() {
+	c.lock()
+	defer c.unlock()
+	for c.pending != nil && len(c.pending) > 0 {
+		<-c.pending
+	}
+	c.lru = nil
+	if c.policy != nil {
+		c.policy.Clear()
+	}
+	c.evictedKeys = nil
+	if c.dedup != nil {
+		c.dedup = make(map[uint64]*blob)
+	}
+	if c.index != nil {
+		c.index = newValueIndex(c.index.fn)
+	}
+	c.tags = nil
+	if c.access != nil {
+		c.access = newAccessStats()
+	}
+	if c.tenants != nil {
+		c.tenants.parts = make(map[string]*tenantPart)
+	}
+}
+
+// overhead 估算缓存除键和值之外的簿记开销（字节）
+// ByteView直接保存在LRU的条目中，其切片头已计入LRU的簿记开销；
+// 最近被淘汰的键的记录同样计入，包括这些键本身占用的字节
+func (c *cache) overhead() int64 {
+	c.lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return 0
+	}
+	n := c.lru.OverheadEstimate()
+	if c.evictedKeys != nil {
+		n += c.evictedKeys.OverheadEstimate() + c.evictedKeys.Bytes()
+	}
+	if c.tenants != nil {
+		// 租户分区与LRU共享键的底层数据，只计入簿记结构
+		for _, p := range c.tenants.parts {
+			n += p.keys.OverheadEstimate()
+		}
+	}
+	return n
+}
+
+// evicted 返回被淘汰的缓存项数量
+func (c *cache) evicted() int64 {
+	c.lock()
+	defer c.unlock()
+	return c.evictions
+}
+
+// bytes 返回缓存当前占用的内存（字节）
+func (c *cache) bytes() int64 {
+	c.lock()
+	defer c.unlock()
+	return c.bytesLocked()
+}
+
+// bytesLocked 与bytes相同，调用方需持有c.mu
+func (c *cache) bytesLocked() int64 {
+	var n int64
+	if c.lru != nil {
+		n = c.lru.Bytes()
+	}
+	if c.policy != nil {
+		n += c.policy.Bytes()
+	}
+	return n
 }
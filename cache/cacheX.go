@@ -9,11 +9,17 @@
 package gocachex
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	pb "goCacheX/gocacheXpb"
+	"goCacheX/lru"
 	"goCacheX/singleflight"
+	"io"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Group 是缓存的命名空间，每个Group拥有一个唯一的名称
@@ -25,6 +31,158 @@ type Group struct {
 
 	peers  PeerPicker          // 通过一致性哈希选择节点
 	loader *singleflight.Group // 防止缓存击穿
+
+	hits   atomic.Int64 // 本地缓存命中次数
+	misses atomic.Int64 // 本地缓存未命中次数
+
+	capacityMisses atomic.Int64 // 未命中的键最近因容量不足被淘汰过的次数
+
+	replicaRetry int            // 远程获取失败时最多尝试的候选节点数，0或1表示只尝试归属节点
+	replicaHits  []atomic.Int64 // 按候选节点序号统计的远程获取成功次数，0为归属节点
+
+	normalizer KeyNormalizer // 可选，在所有操作之前规范化key
+
+	readOnly       bool // 只读节点，从不调用自己的getter
+	noLoadClone    bool // 信任getter返回独占的字节切片，加载时不再复制
+	bufferPool     bool // 加载时从缓冲池分配字节切片，淘汰时归还
+	noSingleflight bool // 不合并并发加载，每次未命中都独立调用getter
+	rejectEmpty    bool // getter返回空值时视为未找到，不写入缓存
+
+	peerErrLog rateLimitedLog        // 远程获取失败的限流日志
+	fallback   atomic.Pointer[Group] // 可选，未命中时的后备分组
+	previewMax int                   // 日志中值预览的最大字节数，0表示不输出值
+	negative   *negativeCache        // 可选，缓存数据不存在的结果
+	janitor    *lru.Janitor          // 开启负缓存时，负责清理过期记录的共享清理器
+	sweep      *rebalanceSweep       // 可选，节点变化后清理不再归属当前节点的缓存项
+
+	metricsSink     MetricsSink   // 可选，统计快照的推送目标
+	metricsInterval time.Duration // 统计快照的推送间隔
+
+	lockThreshold time.Duration // 锁看门狗的报警阈值，0表示不开启
+	onStuck       StuckFunc     // 可选，锁持有超过阈值时调用
+
+	closeOnce sync.Once      // 保证Close只执行一次
+	closed    chan struct{}  // Close时关闭，通知后台协程退出
+	bgMu      sync.Mutex     // 保证Close之后不再启动新的后台协程，Shutdown之后不再开始新的加载
+	bg        sync.WaitGroup // 跟踪属于该分组的后台协程
+	stopping  bool           // Shutdown已开始，拒绝新的加载
+	loads     sync.WaitGroup // 跟踪进行中的加载
+}
+
+// GroupOption 是NewGroup的可选配置项
+type GroupOption func(*Group)
+
+// WithNoLoadClone 关闭getLocally对getter返回值的防御性复制
+//
+// 警告：开启后缓存会直接持有getter返回的字节切片。getter必须保证每次返回的
+// 都是新分配的、此后不会再被任何代码读写或复用的切片（例如不能返回全局变量、
+// 缓冲池中的切片或其他仍在使用的内存）。违反该约定会导致缓存数据被静默篡改，
+// 并且在并发场景下产生数据竞争。只有在确认getter满足约定、且值较大时才建议开启。
+func WithNoLoadClone() GroupOption {
+	return func(g *Group) {
+		g.noLoadClone = true
+	}
+}
+
+// WithLoadHold 设置加载结果的保留窗口
+// 加载成功后的hold时长内，相同key的请求直接复用该次结果，不再触发加载，
+// 可以平滑错峰到达的突发请求。加载失败的结果不会保留，避免把一次请求的超时、取消或临时错误
+// 返回给之后的所有请求；需要缓存数据不存在的结果时使用WithNegativeCache。不能与WithBufferPool同时使用
+func WithLoadHold(hold time.Duration) GroupOption {
+	return func(g *Group) {
+		g.loader.Hold = hold
+	}
+}
+
+// WithBufferPool 让加载的值从全局缓冲池分配内存，并在缓存项被淘汰时归还缓冲池复用
+// 适用于值较大、淘汰频繁的场景，可以显著减少垃圾和GC停顿
+//
+// 不能与WithLoadHold同时使用，否则NewGroup会panic。
+//
+// 警告：开启后，从Get得到的ByteView只在对应缓存项被淘汰之前有效。
+// 缓存项被淘汰后其底层内存会被其他值复用，此时再调用ByteSlice/String读到的
+// 可能是其他key的数据。调用方必须在拿到ByteView后立即读取（例如立刻写入响应），
+// 绝不能长期持有ByteView。需要较长时间读取时使用GetReader，它在Close之前保证内存不被复用。
+func WithBufferPool() GroupOption {
+	return func(g *Group) {
+		g.bufferPool = true
+		g.mainCache.onEvicted = func(key string, value ByteView) {
+			g.mainCache.releaseBuffer(value.b)
+		}
+	}
+}
+
+// WithReplicaRetry 开启远程获取失败后的候选节点重试
+// 归属节点请求失败时，按哈希环顺序继续尝试后续节点，最多尝试replicas个节点，
+// 全部失败后才在本地加载。这样非归属节点不会因为一次失败就调用getter、缓存不属于自己的数据。
+// 需要注册的PeerPicker实现PeersPicker接口，否则仍只尝试归属节点
+func WithReplicaRetry(replicas int) GroupOption {
+	return func(g *Group) {
+		g.replicaRetry = replicas
+	}
+}
+
+// KeyNormalizer 将key转换为规范形式，例如统一大小写、去除首尾空白或做Unicode规范化
+// 实现必须是幂等的：对规范化后的key再次规范化结果不变
+type KeyNormalizer func(key string) string
+
+// WithKeyNormalizer 设置key规范化函数
+// 规范化在查询缓存、选择节点和调用getter之前进行，使 "User:1" 与 " user:1 " 等写法命中同一个缓存项。
+// 由于路由依赖规范化后的key，集群内所有节点的同名Group必须配置相同的规范化函数
+func WithKeyNormalizer(fn KeyNormalizer) GroupOption {
+	return func(g *Group) {
+		g.normalizer = fn
+	}
+}
+
+// WithAdmissionBuffer 开启容量为size的写入缓冲区
+// 大量不同的key同时加载时，每次写入缓存都要竞争同一把锁；开启后加载结果先放入缓冲区，
+// 缓冲区满或有请求未命中时，再在一次加锁中批量写入LRU，从而分摊加锁开销。
+// 注意：刚加载的值在批量写入前不在LRU中，此时Stats中的Bytes/Len以及全局内存上限
+// 都不会计入这些值；Get在未命中时会先写入缓冲区再查询，因此不会因此产生额外的加载
+func WithAdmissionBuffer(size int) GroupOption {
+	return func(g *Group) {
+		if size > 0 {
+			g.mainCache.pending = make(chan admission, size)
+		}
+	}
+}
+
+// WithoutSingleflight 关闭并发加载的合并
+// 默认情况下同一个key的并发未命中只调用一次getter并共享结果，这要求getter是幂等的；
+// 如果getter带有按调用方区分的副作用（例如逐请求记录日志或鉴权），合并结果是错误的。
+//
+// 警告：关闭后热点key失效时，所有并发请求都会同时打到后端，重新引入缓存击穿的风险
+func WithoutSingleflight() GroupOption {
+	return func(g *Group) {
+		g.noSingleflight = true
+	}
+}
+
+// ErrNotFound 表示数据不存在：开启WithRejectEmptyValues时getter返回了空值，
+// getter也可以返回包装了ErrNotFound的错误，配合WithNegativeCache缓存不存在的结果
+var ErrNotFound = errors.New("gocachex: not found")
+
+// WithRejectEmptyValues 将getter返回的空值（长度为0）视为未找到
+// 有些后端在数据不存在时返回空的字节切片而不是错误，开启后这样的结果不会被缓存，
+// Get返回包装了ErrNotFound的错误。合法值可能为空的场景不要开启
+func WithRejectEmptyValues() GroupOption {
+	return func(g *Group) {
+		g.rejectEmpty = true
+	}
+}
+
+// ErrReadOnly 表示只读节点无法从任何加载节点获取数据
+var ErrReadOnly = errors.New("gocachex: read-only node cannot load from the backend")
+
+// WithReadOnlyRole 将当前节点设置为只读角色
+// 只读节点本地未命中时只从远程加载节点获取数据，即使远程获取失败也绝不调用自己的getter，
+// 从而把对后端的访问集中到少数指定节点上。只读节点不应出现在HTTPPool.Set的节点列表中，
+// 否则归属于它自己的key无处加载，会直接返回ErrReadOnly
+func WithReadOnlyRole() GroupOption {
+	return func(g *Group) {
+		g.readOnly = true
+	}
 }
 
 // Getter 定义了当缓存未命中时获取源数据的接口
@@ -45,14 +203,28 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 var (
 	mu     sync.RWMutex
 	groups = make(map[string]*Group) // 全局变量，存储所有Group实例
+
+	// janitor 由注册表持有，在一个协程中定期清理所有分组的TTL缓存（例如负缓存）的过期记录，
+	// 第一个需要清理的分组创建时启动。分组Close时从中注销
+	janitor *lru.Janitor
 )
 
+// janitorInterval 是共享清理器的清理周期
+const janitorInterval = time.Second
+
 // NewGroup 创建一个新的缓存分组实例
-// name: 分组名称，cacheBytes: 缓存最大内存限制，getter: 缓存未命中时的回调
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+// name: 分组名称，cacheBytes: 缓存最大内存限制，getter: 缓存未命中时的回调，opts: 可选配置
+// 注意：cacheBytes为0表示不限制内存（而不是不缓存），此时会输出一条警告日志；cacheBytes不能为负数
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
+	if cacheBytes < 0 {
+		panic("negative cacheBytes")
+	}
+	if cacheBytes == 0 {
+		log.Printf("[GeeCache] group %s has no memory limit (cacheBytes=0 means unbounded)", name)
+	}
 	mu.Lock()
 	defer mu.Unlock()
 	g := &Group{
@@ -60,6 +232,36 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 		getter:    getter,
 		mainCache: cache{cacheBytes: cacheBytes},
 		loader:    &singleflight.Group{},
+		closed:    make(chan struct{}),
+
+		peerErrLog: rateLimitedLog{interval: defaultPeerErrorLogInterval},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.bufferPool {
+		g.mainCache.dedup = nil // 共享的数据不能在单个缓存项淘汰时归还缓冲池
+		if g.loader.Hold > 0 {
+			// 保留窗口内复用的值可能早已被淘汰并归还缓冲池
+			panic("WithBufferPool cannot be combined with WithLoadHold")
+		}
+	}
+	g.replicaHits = make([]atomic.Int64, max(g.replicaRetry, 1))
+	if g.lockThreshold > 0 {
+		g.goBackground(g.watchLock)
+	}
+	if g.metricsSink != nil {
+		g.goBackground(g.flushMetrics)
+	}
+	if g.sweep != nil {
+		g.goBackground(g.sweepLoop)
+	}
+	if g.negative != nil {
+		if janitor == nil {
+			janitor = lru.NewJanitor(janitorInterval)
+		}
+		g.janitor = janitor
+		g.janitor.Register(g.negative)
 	}
 	groups[name] = g
 	return g
@@ -73,52 +275,502 @@ func GetGroup(name string) *Group {
 	return g
 }
 
+// RemoveGroup 从全局注册表中删除指定名称的缓存分组，并调用Close停止其后台协程、释放缓存数据
+// 适用于动态创建大量短生命周期分组（例如按租户划分的缓存）的场景。
+// 删除后GetGroup不再返回该分组，远程节点对它的请求会返回404。返回是否删除了分组
+func RemoveGroup(name string) bool {
+	mu.Lock()
+	g, ok := groups[name]
+	delete(groups, name)
+	mu.Unlock()
+	if ok {
+		g.Close()
+	}
+	return ok
+}
+
+// Close 停止该分组的所有后台协程（例如一致性检查）并释放缓存数据，可以重复调用
+// Close不会从全局注册表中删除分组，需要删除时使用RemoveGroup。
+// 仍持有*Group的调用方可以继续使用，缓存会在下次写入时重新创建，但不会再启动后台任务
+func (g *Group) Close() {
+	g.closeOnce.Do(func() {
+		g.bgMu.Lock()
+		close(g.closed)
+		g.bgMu.Unlock()
+		g.bg.Wait()
+		if g.janitor != nil {
+			g.janitor.Unregister(g.negative)
+		}
+		g.mainCache.clear()
+	})
+}
+
+// ErrShutdown 表示分组正在关闭，不再开始新的加载
+var ErrShutdown = errors.New("gocachex: group is shutting down")
+
+// Shutdown 优雅地关闭分组：不再开始新的加载（返回ErrShutdown，已缓存的数据仍可读取），
+// 等待进行中的加载（包括在singleflight上等待结果的请求）完成后调用Close释放资源。
+// ctx先于加载完成结束时不再等待，仍然调用Close并返回ctx.Err()，未完成的加载会继续运行直到返回
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.bgMu.Lock()
+	g.stopping = true
+	g.bgMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.loads.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	g.Close()
+	return err
+}
+
+// beginLoad 登记一次加载，Shutdown开始后返回false
+func (g *Group) beginLoad() bool {
+	g.bgMu.Lock()
+	defer g.bgMu.Unlock()
+	if g.stopping {
+		return false
+	}
+	g.loads.Add(1)
+	return true
+}
+
+// goBackground 启动一个属于该分组的后台协程，fn应在g.closed关闭时尽快返回
+// 分组已关闭时不启动并返回false
+func (g *Group) goBackground(fn func()) bool {
+	g.bgMu.Lock()
+	defer g.bgMu.Unlock()
+	select {
+	case <-g.closed:
+		return false
+	default:
+	}
+	g.bg.Add(1)
+	go func() {
+		defer g.bg.Done()
+		fn()
+	}()
+	return true
+}
+
 // Get 从缓存获取键对应的值，如果缓存中不存在，则调用load方法加载
 func (g *Group) Get(key string) (ByteView, error) {
+	key = g.normalize(key)
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
 
-	bytes, ok := g.mainCache.get(key)
+	bytes, ok := g.lookup(key)
 	if ok {
-		log.Println("[GeeCache] hit")
+		g.logHit(key, bytes)
 		return bytes, nil
 	}
 	return g.load(key)
 }
 
+// Delete 删除本地缓存中的key，返回key是否在缓存中
+// 正在由getter加载的key即使不在缓存中也会被标记：加载的值仍返回给等待的调用方，但不再写入缓存，
+// 避免删除与加载竞争时把删除之前读到的旧数据重新写回缓存。开启WithLoadHold时，
+// 保留窗口内未命中的Get仍会得到删除之前加载的结果。只影响本节点的缓存
+func (g *Group) Delete(key string) bool {
+	return g.mainCache.remove(g.normalize(key))
+}
+
+// GetReader 获取key对应的值并返回一个读取它的io.ReadCloser以及值的总长度
+// 未命中时会先加载。缓存值是只读的，读取器直接读取缓存中的数据而不复制，
+// 适合用io.Copy把大对象写入HTTP响应。开启WithBufferPool时读取器持有对值内存的引用，
+// 缓存项在Close之前被淘汰时内存推迟到Close时才归还缓冲池；调用方必须Close读取器，
+// 否则这部分内存不会再被复用（但仍会被GC回收）。值在加载之后、加引用之前就被淘汰时重新加载，
+// 多次仍无法持有引用时返回错误
+func (g *Group) GetReader(key string) (io.ReadCloser, int64, error) {
+	v, err := g.Get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !g.bufferPool {
+		return io.NopCloser(v.Reader()), int64(v.Len()), nil
+	}
+	key = g.normalize(key)
+	for attempt := 0; ; attempt++ {
+		if pv, ok := g.mainCache.pin(key); ok {
+			r := &pinnedReader{Reader: pv.Reader(), c: &g.mainCache, v: pv}
+			return r, int64(pv.Len()), nil
+		}
+		if g.mainCache.cacheBytes > 0 && int64(len(key)+v.Len()) > g.mainCache.cacheBytes {
+			// 值超过cacheBytes，在写入过程中就被淘汰，内存已交给GC而不会被复用，可以直接读取
+			return io.NopCloser(v.Reader()), int64(v.Len()), nil
+		}
+		if attempt == maxPinAttempts {
+			return nil, 0, fmt.Errorf("gocachex: key %q was evicted before it could be read", key)
+		}
+		// 值在返回之后被淘汰，内存可能已被复用，不能再读取，重新加载
+		if v, err = g.Get(key); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// maxPinAttempts 是GetReader在值被淘汰后重新加载的最大次数
+const maxPinAttempts = 3
+
+// GetAsync 只查询本地缓存，命中时返回(value, true)
+// 未命中时立即返回(ByteView{}, false)，同时在后台发起加载（与其他请求合并），
+// 加载完成后的请求即可命中。适用于可以先展示占位内容、稍后再刷新的场景。
+// 后台加载的错误会被丢弃，只记录日志。后台加载计入Shutdown等待的加载，
+// 分组开始关闭后不再发起后台加载
+func (g *Group) GetAsync(key string) (ByteView, bool) {
+	key = g.normalize(key)
+	if key == "" {
+		return ByteView{}, false
+	}
+	if v, ok := g.lookup(key); ok {
+		return v, true
+	}
+	if !g.beginLoad() {
+		return ByteView{}, false
+	}
+	go func() {
+		defer g.loads.Done()
+		if _, err := g.load(key); err != nil && !errors.Is(err, ErrShutdown) {
+			log.Println("[GeeCache] async load failed", err)
+		}
+	}()
+	return ByteView{}, false
+}
+
+// normalize 使用配置的规范化函数处理key，未配置时原样返回
+func (g *Group) normalize(key string) string {
+	if g.normalizer == nil {
+		return key
+	}
+	return g.normalizer(key)
+}
+
+// lookup 查询本地缓存并记录命中/未命中次数
+func (g *Group) lookup(key string) (ByteView, bool) {
+	v, ok := g.mainCache.getCounted(key, true)
+	if ok {
+		g.hits.Add(1)
+	} else {
+		g.misses.Add(1)
+		if g.mainCache.recentlyEvicted(key) {
+			g.capacityMisses.Add(1)
+		}
+	}
+	return v, ok
+}
+
+// Stats 是Group的运行统计快照
+type Stats struct {
+	Hits      int64 // 本地缓存命中次数
+	Misses    int64 // 本地缓存未命中次数
+	Evictions int64 // 本地缓存淘汰的条目数
+	Bytes     int64 // 本地缓存占用的内存（字节）
+	Len       int   // 本地缓存的条目数
+
+	// CapacityMisses 是未命中中键最近因容量不足被淘汰过的次数，ColdMisses 是其余的未命中
+	// （键从未缓存过，或被淘汰得太早已不在记录中）。容量未命中占比高说明增大cacheBytes有帮助
+	CapacityMisses int64
+	ColdMisses     int64
+
+	// RebalanceEvictions 是节点变化后因归属其他节点被清理的缓存项数量，见WithRebalanceSweep
+	RebalanceEvictions int64
+
+	// ReplicaHits 按候选节点序号统计远程获取成功的次数，下标0为归属节点（主节点），
+	// 其余为WithReplicaRetry开启后依次尝试的后续节点。后续节点的计数持续增长
+	// 通常说明主节点不可达
+	ReplicaHits []int64
+}
+
+// HitRatio 返回命中率，没有任何请求时返回0
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Stats 返回Group当前的统计信息
+func (g *Group) Stats() Stats {
+	// 先读取容量未命中，保证并发更新时ColdMisses不为负数
+	capacityMisses := g.capacityMisses.Load()
+	misses := g.misses.Load()
+	return Stats{
+		Hits:      g.hits.Load(),
+		Misses:    misses,
+		Evictions: g.mainCache.evicted(),
+		Bytes:     g.mainCache.bytes(),
+		Len:       g.mainCache.Len(),
+
+		CapacityMisses: capacityMisses,
+		ColdMisses:     misses - capacityMisses,
+
+		RebalanceEvictions: g.sweep.evicted(),
+
+		ReplicaHits: g.replicaStats(),
+	}
+}
+
+// replicaStats 返回按候选节点序号统计的远程获取成功次数
+func (g *Group) replicaStats() []int64 {
+	hits := make([]int64, len(g.replicaHits))
+	for i := range g.replicaHits {
+		hits[i] = g.replicaHits[i].Load()
+	}
+	return hits
+}
+
+// MemoryOverheadEstimate 估算本地缓存除键和值之外的簿记开销（字节）
+// 包括LRU链表节点、条目结构体、map槽位以及每个值的切片头，不包括Stats().Bytes已统计的键和值。
+// 估算按64位平台的结构体大小计算，忽略内存分配器的尺寸取整和GC的额外空间，实际占用会更高，
+// 可用于解释"1GB缓存"为何占用明显更多的RSS，并据此预留容器内存
+func (g *Group) MemoryOverheadEstimate() int64 {
+	return g.mainCache.overhead()
+}
+
+// OldestKeys 返回本地缓存中最久未使用的n个键，按从旧到新排列
+// 这些键是即将被淘汰的候选，可用于提前刷新较热的数据，调用不会影响淘汰顺序
+func (g *Group) OldestKeys(n int) []string {
+	return g.mainCache.oldestN(n)
+}
+
+// Timings 记录一次GetTimed调用中各阶段的耗时，单位为纳秒
+// 未经过的阶段保持为0；如果本次调用复用了其他请求正在进行的加载，
+// 只有Wait会被记录，Peer和Getter由发起加载的那次调用记录
+type Timings struct {
+	Lookup int64 // 本地缓存查找耗时
+	Wait   int64 // 在singleflight中等待加载完成的总耗时，包含Peer和Getter
+	Peer   int64 // 向远程节点请求数据的往返耗时，重试多个节点时为总和
+	Getter int64 // 调用本地getter加载数据的耗时
+}
+
+// GetTimed 与Get相同，同时返回本次调用各阶段的耗时，用于定位延迟问题
+// 相比Get有少量额外开销，仅建议在调试时使用
+func (g *Group) GetTimed(key string) (ByteView, Timings, error) {
+	var tm Timings
+	key = g.normalize(key)
+	if key == "" {
+		return ByteView{}, tm, fmt.Errorf("key is required")
+	}
+
+	start := time.Now()
+	v, ok := g.lookup(key)
+	tm.Lookup = time.Since(start).Nanoseconds()
+	if ok {
+		g.logHit(key, v)
+		return v, tm, nil
+	}
+	v, err := g.loadTimed(context.Background(), key, &tm)
+	return v, tm, err
+}
+
+// ErrTimeout 表示GetContext在ctx的截止时间到达前未能拿到数据
+// 返回的错误同时满足errors.Is(err, ErrTimeout)和errors.Is(err, context.DeadlineExceeded)
+var ErrTimeout = errors.New("gocachex: get timed out")
+
+// GetContext 与Get相同，但会在ctx被取消或超时时立即返回
+// 超时返回的错误可以通过errors.Is(err, context.DeadlineExceeded)与后端错误区分开。
+// 向远程节点的请求受ctx控制，ctx结束时请求被取消，且不会再回退到本地getter；
+// 本地getter不支持取消，已经发起的本地加载完成后仍会写入缓存，供后续请求命中。
+// 并发请求同一个key时共享一次加载，它们会得到发起加载的请求因ctx结束而产生的错误
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
+	key = g.normalize(key)
+	if key == "" {
+		return ByteView{}, fmt.Errorf("key is required")
+	}
+	if err := ctx.Err(); err != nil {
+		return ByteView{}, contextError(key, err)
+	}
+
+	if v, ok := g.lookup(key); ok {
+		g.logHit(key, v)
+		return v, nil
+	}
+
+	type result struct {
+		value ByteView
+		err   error
+	}
+	ch := make(chan result, 1) // 带缓冲，ctx先结束时加载协程也不会阻塞
+	go func() {
+		value, err := g.loadTimed(ctx, key, nil)
+		ch <- result{value, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.value, r.err
+	case <-ctx.Done():
+		return ByteView{}, contextError(key, ctx.Err())
+	}
+}
+
+// contextError 包装ctx结束的原因，超时的情况额外包装ErrTimeout
+func contextError(key string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: key %q: %w", ErrTimeout, key, err)
+	}
+	return fmt.Errorf("gocachex: get key %q: %w", key, err)
+}
+
 func (g *Group) RegisterPeers(peers PeerPicker) {
 	if g.peers != nil {
 		panic("RegisterPeerPicker called more than once")
 	}
 	g.peers = peers
+	if w, ok := peers.(RingWatcher); ok && g.sweep != nil {
+		w.OnRingChange(g.sweep.notify)
+	}
 }
 
 // load 加载键对应的值，可以从本地或远程获取
-func (g *Group) load(key string) (value ByteView, err error) { //返回值变量在函数开始时就已声明和初始化可以直接在函数体内使用这些变量不需要显式 return 具体的值，可以直接 return适合需要多次修改返回值的情况
+func (g *Group) load(key string) (value ByteView, err error) {
+	return g.loadTimed(context.Background(), key, nil)
+}
+
+// getForwarded 处理其他节点转发来的请求：未命中时只在本地加载，不再转发给其他节点
+// 转发方已经按它的哈希环选中了本节点，即使本节点认为归属其他节点（例如归属节点正在下线），
+// 再次转发也可能被送回转发方，形成循环
+func (g *Group) getForwarded(ctx context.Context, key string) (ByteView, error) {
+	key = g.normalize(key)
+	if key == "" {
+		return ByteView{}, fmt.Errorf("key is required")
+	}
+	if v, ok := g.lookup(key); ok {
+		g.logHit(key, v)
+		return v, nil
+	}
+	return g.loadFrom(ctx, key, nil, func(string) []PeerGetter { return nil })
+}
+
+// loadTimed 与load相同，tm不为nil时记录加载过程中各阶段的耗时
+// ctx控制向远程节点的请求，ctx结束导致的错误直接返回，不再尝试其他节点或本地getter
+func (g *Group) loadTimed(ctx context.Context, key string, tm *Timings) (value ByteView, err error) {
+	return g.loadFrom(ctx, key, tm, g.pickPeers)
+}
 
-	view, err := g.loader.Do(key, func() (any, error) {
-		if g.peers != nil {
-			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
-					return value, nil
+// loadFrom 与loadTimed相同，依次尝试pick返回的远程节点，全部失败后在本地加载
+func (g *Group) loadFrom(ctx context.Context, key string, tm *Timings, pick func(key string) []PeerGetter) (value ByteView, err error) { //返回值变量在函数开始时就已声明和初始化可以直接在函数体内使用这些变量不需要显式 return 具体的值，可以直接 return适合需要多次修改返回值的情况
+	if !g.beginLoad() {
+		return ByteView{}, ErrShutdown
+	}
+	defer g.loads.Done()
+	start := time.Now()
+	fn := func() (any, error) {
+		// 最近确认不存在的key不再请求远程节点和getter，但仍会查询后备分组
+		if err := g.negative.get(key); err != nil {
+			return nil, err
+		}
+		for rank, peer := range pick(key) {
+			peerStart := time.Now()
+			value, err := g.getFromPeer(ctx, peer, key)
+			if tm != nil {
+				tm.Peer += time.Since(peerStart).Nanoseconds()
+			}
+			if err == nil {
+				if rank < len(g.replicaHits) {
+					g.replicaHits[rank].Add(1)
 				}
-				log.Println("[GeeCache] Failed to get from peer", err)
+				return value, nil
 			}
+			if errors.Is(err, ErrNotFound) {
+				// 归属节点确认数据不存在，本地加载也只会得到相同的结果
+				return nil, err
+			}
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				// 请求超时或被取消，调用方已不再等待结果，不回退到本地getter
+				return nil, err
+			}
+			g.peerErrLog.println(peerName(peer), "[GeeCache] Failed to get from peer", err.Error())
 		}
-		return g.getLocally(key)
-	})
+		if g.readOnly {
+			return nil, ErrReadOnly
+		}
+		getterStart := time.Now()
+		value, err := g.getLocally(key)
+		if tm != nil {
+			tm.Getter = time.Since(getterStart).Nanoseconds()
+		}
+		return value, err
+	}
+	var view any
+	if g.noSingleflight {
+		view, err = fn()
+	} else {
+		// 等待的分阶段写入被放弃时重新加载，见BeginFill
+		for {
+			view, err = g.loader.Do(key, fn)
+			if !errors.Is(err, errFillAborted) {
+				break
+			}
+		}
+	}
+	if tm != nil {
+		tm.Wait = time.Since(start).Nanoseconds()
+	}
 
 	if err == nil {
 		return view.(ByteView), nil
 	}
+	if fb := g.fallback.Load(); fb != nil {
+		return fb.Get(key)
+	}
 	return ByteView{}, err
 }
 
+// fallbackMu 串行化所有分组的SetMissFallback，使环检查和设置是原子的
+var fallbackMu sync.Mutex
+
+// SetMissFallback 设置未命中时的后备分组，nil表示取消
+// 本分组从远程节点和getter都加载失败后，改为返回后备分组Get的结果，后备分组的值不会写入本分组的缓存。
+// 可以在稳定的数据源之后叠加一个实验性的数据源（A/B或金丝雀），而无需修改getter。
+// 设置会形成环（后备链最终回到本分组）时返回错误且不生效
+func (g *Group) SetMissFallback(other *Group) error {
+	// 所有分组的设置互斥进行，避免并发的A→B和B→A各自通过检查后形成环
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	for fb := other; fb != nil; fb = fb.fallback.Load() {
+		if fb == g {
+			return fmt.Errorf("miss fallback %s would form a cycle with %s", other.name, g.name)
+		}
+	}
+	g.fallback.Store(other)
+	return nil
+}
+
+// pickPeers 返回需要依次尝试的远程节点，key归属本节点时返回空
+func (g *Group) pickPeers(key string) []PeerGetter {
+	if g.peers == nil {
+		return nil
+	}
+	if pp, ok := g.peers.(PeersPicker); ok && g.replicaRetry > 1 {
+		return pp.PickPeers(key, g.replicaRetry)
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		return []PeerGetter{peer}
+	}
+	return nil
+}
+
 // getLocally 从本地数据源获取原始数据，转换为ByteView并添加到缓存
+// 加载期间通过Delete等删除了该键时，加载的值仍返回给调用方，但不写入缓存，避免删除之后重新写入旧数据
 func (g *Group) getLocally(key string) (ByteView, error) {
+	ticket := g.mainCache.loadStart(key)
+	defer g.mainCache.loadEnd(key, ticket)
 	bytes, err := g.getter.Get(key)
+	if err == nil && g.rejectEmpty && len(bytes) == 0 {
+		err = fmt.Errorf("%w: getter returned an empty value for key %s", ErrNotFound, key)
+	}
 	if err != nil {
+		g.negative.add(key, err)
 		return ByteView{}, err
 	}
 
@@ -128,23 +780,61 @@ func (g *Group) getLocally(key string) (ByteView, error) {
 	// 3. 内存所有权清晰：缓存系统完全控制这部分内存，不依赖外部代码的内存管理
 	// 4. 并发安全考虑：不可变数据更适合在并发环境中使用，减少潜在的竞态条件
 	// 虽然有轻微性能开销，但换来更好的数据安全性和系统稳定性
-	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	// 开启WithNoLoadClone时，由getter保证返回的切片归缓存独占，跳过这次复制
+	switch {
+	case g.bufferPool:
+		buf := getBuffer(len(bytes))
+		copy(buf, bytes)
+		bytes = buf
+	case !g.noLoadClone:
+		bytes = cloneBytes(bytes)
+	}
+	value := ByteView{b: bytes}
+	g.populate(key, value, 0, ticket)
 	return value, nil
 }
 
 // populateCache 将键值对添加到缓存
+// 开启缓冲池时同步写入，写入过程中（包括全局内存上限的淘汰）被淘汰的值不归还缓冲池，
+// 因为加载方和所有等待者随后仍会读取它
 func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
+	g.populateCacheTTL(key, value, 0)
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+// populateCacheTTL 与populateCache相同，ttl大于0时缓存项在ttl后过期，此时不经过写入缓冲区
+func (g *Group) populateCacheTTL(key string, value ByteView, ttl time.Duration) {
+	g.populate(key, value, ttl, loadTicket{})
+}
+
+// populate 与populateCacheTTL相同，ticket对应的加载开始后键被删除过时不写入
+func (g *Group) populate(key string, value ByteView, ttl time.Duration, ticket loadTicket) {
+	g.negative.remove(key)
+	switch {
+	case g.bufferPool:
+		g.mainCache.hold(value.b)
+		defer g.mainCache.unhold(value.b)
+		g.mainCache.addLoaded(key, value, ttl, ticket)
+	case ttl > 0:
+		g.mainCache.addLoaded(key, value, ttl, ticket)
+	default:
+		g.mainCache.admit(key, value, ticket)
+	}
+	enforceGlobalMaxBytes()
+}
+
+// getFromPeer 从远程节点获取数据，节点实现ContextPeerGetter时请求受ctx控制
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	var err error
+	if cp, ok := peer.(ContextPeerGetter); ok {
+		err = cp.GetContext(ctx, req, res)
+	} else {
+		err = peer.Get(req, res)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
@@ -1,10 +1,29 @@
 package gocachex
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	pb "goCacheX/gocacheXpb"
+	"goCacheX/lru"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -65,3 +84,1952 @@ func TestGet(t *testing.T) {
 		t.Fatalf("the value of unknow should be empty, but %s got", view)
 	}
 }
+
+func TestNoLoadClone(t *testing.T) {
+	gee := NewGroup("no-load-clone", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			// 每次返回新分配的切片，满足WithNoLoadClone的约定
+			return []byte("value-of-" + key), nil
+		}), WithNoLoadClone())
+
+	for i := 0; i < 2; i++ {
+		view, err := gee.Get("Tom")
+		if err != nil || view.String() != "value-of-Tom" {
+			t.Fatalf("expect value-of-Tom, got %q (err=%v)", view.String(), err)
+		}
+	}
+
+	// 修改读出的副本不能影响缓存中的值
+	view, _ := gee.Get("Tom")
+	b := view.ByteSlice()
+	b[0] = 'X'
+	if view, _ := gee.Get("Tom"); view.String() != "value-of-Tom" {
+		t.Fatalf("cached value was modified: %q", view.String())
+	}
+}
+
+func benchmarkLoad(b *testing.B, opts ...GroupOption) {
+	value := make([]byte, 1<<20)
+	gee := NewGroup(b.Name(), 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			return append([]byte(nil), value...), nil
+		}), opts...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// 每次使用不同的key，保证都走加载路径
+		if _, err := gee.load(fmt.Sprintf("key-%d", i)); err != nil {
+			b.Fatal(err)
+		}
+		gee.mainCache = cache{}
+	}
+}
+
+func BenchmarkLoadClone(b *testing.B) {
+	benchmarkLoad(b)
+}
+
+func BenchmarkLoadNoClone(b *testing.B) {
+	benchmarkLoad(b, WithNoLoadClone())
+}
+
+func TestOldestKeys(t *testing.T) {
+	gee := NewGroup("oldest-keys", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	for _, k := range []string{"a", "b", "c"} {
+		gee.Get(k)
+	}
+	gee.Get("a")
+
+	if got, expect := gee.OldestKeys(2), []string{"b", "c"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, got %v", expect, got)
+	}
+}
+
+func TestGetContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	gee := NewGroup("get-context", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			<-release
+			return []byte(key), nil
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := gee.GetContext(ctx, "slow")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+}
+
+func TestHTTPGetterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	getter := &httpGetter{
+		baseURL: server.URL + defaultBasePath,
+		client:  &http.Client{Timeout: 20 * time.Millisecond},
+	}
+	err := getter.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+}
+
+func TestGetContextPeerDeadline(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // 直到客户端取消请求
+		cancelled <- struct{}{}
+	}))
+	defer server.Close()
+
+	var local atomic.Int64
+	gee := NewGroup("get-context-peer", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			local.Add(1)
+			return []byte(key), nil
+		}))
+	gee.RegisterPeers(fakePeers{&httpGetter{baseURL: server.URL + defaultBasePath}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the peer request should be cancelled with ctx")
+	}
+
+	// HTTP客户端超时同样返回给调用方，而不是回退到本地getter
+	gee2 := NewGroup("get-peer-timeout", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			local.Add(1)
+			return []byte(key), nil
+		}))
+	gee2.RegisterPeers(fakePeers{&httpGetter{
+		baseURL: server.URL + defaultBasePath,
+		client:  &http.Client{Timeout: 20 * time.Millisecond},
+	}})
+	if _, err := gee2.Get("slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded from the peer, got %v", err)
+	}
+	if n := local.Load(); n != 0 {
+		t.Fatalf("peer timeouts should not fall back to the local getter, called %d times", n)
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	gee := NewGroup("buffer-pool", 64, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(strings.Repeat(key, 10)), nil
+		}), WithBufferPool())
+
+	// 容量只够容纳少量值，不断加载新key触发淘汰和缓冲区复用
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("%03d", i)
+		view, err := gee.Get(key)
+		if err != nil || view.String() != strings.Repeat(key, 10) {
+			t.Fatalf("key %s: unexpected value %q (err=%v)", key, view.String(), err)
+		}
+	}
+}
+
+func benchmarkChurn(b *testing.B, opts ...GroupOption) {
+	value := make([]byte, 64<<10)
+	gee := NewGroup(b.Name(), 1<<20, GetterFunc(
+		func(key string) ([]byte, error) { return value, nil }), opts...)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gee.Get(strconv.Itoa(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+	b.ReportMetric(float64(after.NumGC-before.NumGC), "gcs")
+}
+
+func BenchmarkChurn(b *testing.B) {
+	benchmarkChurn(b)
+}
+
+func BenchmarkChurnBufferPool(b *testing.B) {
+	benchmarkChurn(b, WithBufferPool())
+}
+
+func TestGetTimed(t *testing.T) {
+	gee := NewGroup("get-timed", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			time.Sleep(5 * time.Millisecond)
+			return []byte(key), nil
+		}))
+
+	view, tm, err := gee.GetTimed("Tom")
+	if err != nil || view.String() != "Tom" {
+		t.Fatalf("unexpected result %q (err=%v)", view.String(), err)
+	}
+	if tm.Lookup < 0 || tm.Wait <= 0 || tm.Getter < int64(5*time.Millisecond) {
+		t.Fatalf("phases not populated on miss: %+v", tm)
+	}
+	if tm.Wait < tm.Getter || tm.Peer != 0 {
+		t.Fatalf("unexpected timings: %+v", tm)
+	}
+
+	// 命中时只记录查找耗时
+	if _, tm, _ := gee.GetTimed("Tom"); tm.Wait != 0 || tm.Getter != 0 {
+		t.Fatalf("expect only lookup on hit, got %+v", tm)
+	}
+}
+
+// fakePeers 将所有键路由到同一个fakePeer
+type fakePeers struct{ peer PeerGetter }
+
+func (p fakePeers) PickPeer(key string) (PeerGetter, bool) { return p.peer, p.peer != nil }
+
+// fakePeer 从内存map返回数据，不存在的键返回错误
+type fakePeer struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (p *fakePeer) Get(in *pb.Request, out *pb.Response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v, ok := p.data[in.GetKey()]
+	if !ok {
+		return fmt.Errorf("%s not exist", in.GetKey())
+	}
+	out.Value = []byte(v)
+	return nil
+}
+
+func TestConsistencyCheck(t *testing.T) {
+	gee := NewGroup("consistency-check", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(db[key]), nil }))
+	// 本地缓存先写入数据，之后再注册远程节点
+	for k := range db {
+		gee.Get(k)
+	}
+
+	peer := &fakePeer{data: map[string]string{"Tom": "630", "Jack": "589", "Sam": "999"}}
+	gee.RegisterPeers(fakePeers{peer})
+
+	mismatched := make(chan string, len(db))
+	if n := gee.checkConsistency(len(db), func(key string, local, remote ByteView) {
+		mismatched <- key
+	}); n != 1 {
+		t.Fatalf("expect 1 mismatch, got %d", n)
+	}
+	if key := <-mismatched; key != "Sam" {
+		t.Fatalf("expect Sam flagged, got %s", key)
+	}
+
+	// 后台检查同样能发现不一致
+	stop := gee.StartConsistencyCheck(10*time.Millisecond, len(db), func(key string, local, remote ByteView) {
+		select {
+		case mismatched <- key:
+		default:
+		}
+	})
+	defer stop()
+	select {
+	case key := <-mismatched:
+		if key != "Sam" {
+			t.Fatalf("expect Sam flagged, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("background checker did not flag the divergence")
+	}
+
+	// stop可以并发调用，每个调用都等待后台协程退出
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHTTPGetterIfModified(t *testing.T) {
+	value := strings.Repeat("v", 1024)
+	NewGroup("if-modified", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(value), nil }))
+	server := httptest.NewServer(NewHTTPPool("localhost:9999"))
+	defer server.Close()
+
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+	req := &pb.Request{Group: "if-modified", Key: "big"}
+
+	res := &pb.Response{}
+	modified, err := getter.GetIfModified(req, res, ByteView{b: []byte(value)}.Version())
+	if err != nil || modified || len(res.Value) != 0 {
+		t.Fatalf("expect not modified without body, got modified=%v len=%d err=%v", modified, len(res.Value), err)
+	}
+
+	modified, err = getter.GetIfModified(req, res, ByteView{b: []byte("stale")}.Version())
+	if err != nil || !modified || string(res.Value) != value {
+		t.Fatalf("expect full value on version mismatch, got modified=%v err=%v", modified, err)
+	}
+}
+
+func TestEvictOldest(t *testing.T) {
+	var c cache
+	c.add("k1", ByteView{b: []byte("1")})
+	c.add("k2", ByteView{b: []byte("2")})
+	c.get("k1")
+
+	c.evictOldest()
+	if _, ok := c.get("k2"); ok {
+		t.Fatal("k2 should be evicted")
+	}
+	if _, ok := c.get("k1"); !ok || c.Len() != 1 {
+		t.Fatal("k1 should remain cached")
+	}
+	if c.evicted() != 1 {
+		t.Fatalf("expect 1 eviction, got %d", c.evicted())
+	}
+}
+
+// TestGlobalMaxBytesBadPolicy 测试淘汰策略返回不在候选列表中的Group时不会无限循环
+func TestGlobalMaxBytesBadPolicy(t *testing.T) {
+	defer SetGlobalMaxBytes(0)
+	defer SetVictimPolicy(nil)
+
+	gee := NewGroup("governor-bad-policy", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	gee.Get("a")
+	empty := &Group{name: "governor-outsider"}
+	SetVictimPolicy(func(candidates []*Group) *Group { return empty })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SetGlobalMaxBytes(1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enforcing the cap should stop when the policy picks a non-candidate")
+	}
+	if gee.Stats().Len != 1 {
+		t.Fatalf("no group should be evicted, len=%d", gee.Stats().Len)
+	}
+}
+
+// stuckPolicy 是无法再淘汰缓存项的淘汰策略
+type stuckPolicy struct{ *lru.Cache[lru.Value] }
+
+func (stuckPolicy) RemoveOldest() {}
+
+// TestGlobalMaxBytesStuckPolicy 测试迁移后的淘汰策略无法释放内存时全局淘汰跳过该Group并结束
+func TestGlobalMaxBytesStuckPolicy(t *testing.T) {
+	defer SetGlobalMaxBytes(0)
+	defer SetVictimPolicy(nil)
+
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(strings.Repeat("v", 100)), nil })
+	stuck := NewGroup("governor-stuck", 0, getter)
+	defer stuck.Close()
+	other := NewGroup("governor-stuck-other", 0, getter)
+	defer other.Close()
+	if err := stuck.MigratePolicy(stuckPolicy{lru.New(0, nil)}); err != nil {
+		t.Fatal(err)
+	}
+	stuck.Get("s")
+	other.Get("o")
+	// 总是优先选择无法淘汰的Group，它被移出候选后转而淘汰other
+	SetVictimPolicy(func(candidates []*Group) *Group {
+		if slices.Contains(candidates, stuck) {
+			return stuck
+		}
+		if slices.Contains(candidates, other) {
+			return other
+		}
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SetGlobalMaxBytes(1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enforcing the cap should skip a group whose policy cannot evict")
+	}
+	if stuck.Stats().Len != 1 || other.Stats().Len != 0 {
+		t.Fatalf("expect only the other group to be evicted, got %d and %d", stuck.Stats().Len, other.Stats().Len)
+	}
+}
+
+// TestGlobalMaxBytesBatch 测试全局淘汰在一次选择中淘汰一批缓存项，而不是每淘汰一个都重新选择
+func TestGlobalMaxBytesBatch(t *testing.T) {
+	defer SetGlobalMaxBytes(0)
+	defer SetVictimPolicy(nil)
+
+	gee := NewGroup("governor-batch", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(strings.Repeat("v", 100)), nil }))
+	defer gee.Close()
+	for i := 0; i < 10; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	var picks int
+	SetVictimPolicy(func(candidates []*Group) *Group {
+		if !slices.Contains(candidates, gee) {
+			return nil
+		}
+		picks++
+		return gee
+	})
+
+	var total int64
+	mu.RLock()
+	for _, g := range groups {
+		total += g.Stats().Bytes
+	}
+	mu.RUnlock()
+	SetGlobalMaxBytes(total - 5*101 + 1)
+	if gee.Stats().Len != 5 || picks != 1 {
+		t.Fatalf("expect 5 entries evicted in one pick, got len=%d picks=%d", gee.Stats().Len, picks)
+	}
+}
+
+func TestGlobalMaxBytes(t *testing.T) {
+	defer SetGlobalMaxBytes(0)
+	defer SetVictimPolicy(nil)
+
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return []byte(strings.Repeat("v", 100)), nil
+	})
+	hot := NewGroup("governor-hot", 0, getter)
+	cold := NewGroup("governor-cold", 0, getter)
+	// 其他测试创建的Group也在全局注册表中，只在这两个Group之间按命中率选择
+	SetVictimPolicy(func(candidates []*Group) *Group {
+		var ours []*Group
+		for _, g := range candidates {
+			if g == hot || g == cold {
+				ours = append(ours, g)
+			}
+		}
+		return LowestHitRatio(ours)
+	})
+
+	// hot 组反复命中，命中率高
+	hot.Get("h0")
+	for i := 0; i < 10; i++ {
+		hot.Get("h0")
+	}
+	for i := 0; i < 5; i++ {
+		cold.Get(fmt.Sprintf("c%d", i))
+	}
+
+	var total int64
+	mu.RLock()
+	for _, g := range groups {
+		total += g.Stats().Bytes
+	}
+	mu.RUnlock()
+	others := total - hot.Stats().Bytes - cold.Stats().Bytes
+	limit := total - 150
+	SetGlobalMaxBytes(limit)
+
+	if used := others + hot.Stats().Bytes + cold.Stats().Bytes; used > limit {
+		t.Fatalf("total bytes %d exceeds global cap %d", used, limit)
+	}
+	if hot.Stats().Len != 1 {
+		t.Fatalf("hot group should keep its entry, len=%d", hot.Stats().Len)
+	}
+	if cold.Stats().Len != 3 {
+		t.Fatalf("cold group should lose 2 entries, len=%d", cold.Stats().Len)
+	}
+
+	// 后续加载同样受全局上限约束
+	for i := 5; i < 10; i++ {
+		cold.Get(fmt.Sprintf("c%d", i))
+	}
+	if used := others + hot.Stats().Bytes + cold.Stats().Bytes; used > limit {
+		t.Fatalf("total bytes %d exceeds global cap %d", used, limit)
+	}
+	if hot.Stats().Len != 1 {
+		t.Fatalf("hot group should keep its entry, len=%d", hot.Stats().Len)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	PublishExpvar()
+	PublishExpvar() // 重复调用不应panic
+
+	gee := NewGroup("expvar", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	gee.Get("Tom")
+	gee.Get("Tom")
+	gee.Get("Jack")
+
+	var vars map[string]Stats
+	if err := json.Unmarshal([]byte(expvar.Get("gocachex").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	got := vars["expvar"]
+	if got.Hits != 1 || got.Misses != 2 || got.Len != 2 {
+		t.Fatalf("unexpected expvar stats: %+v", got)
+	}
+}
+
+// fakeReplicas 按顺序返回所有候选节点
+type fakeReplicas []PeerGetter
+
+func (p fakeReplicas) PickPeer(key string) (PeerGetter, bool) { return p[0], true }
+
+func (p fakeReplicas) PickPeers(key string, n int) []PeerGetter {
+	if n > len(p) {
+		n = len(p)
+	}
+	return p[:n]
+}
+
+func TestReplicaRetry(t *testing.T) {
+	var getterCalls atomic.Int32
+	gee := NewGroup("replica-retry", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls.Add(1)
+			return []byte("local"), nil
+		}), WithReplicaRetry(2))
+
+	primary := &fakePeer{data: map[string]string{}} // 主节点不可用
+	secondary := &fakePeer{data: map[string]string{"Tom": "630"}}
+	gee.RegisterPeers(fakeReplicas{primary, secondary})
+
+	view, err := gee.Get("Tom")
+	if err != nil || view.String() != "630" {
+		t.Fatalf("expect secondary to serve 630, got %q (err=%v)", view.String(), err)
+	}
+	if getterCalls.Load() != 0 {
+		t.Fatalf("getter should not run when a replica serves, ran %d times", getterCalls.Load())
+	}
+	if hits := gee.Stats().ReplicaHits; len(hits) != 2 || hits[0] != 0 || hits[1] != 1 {
+		t.Fatalf("expect the secondary to be recorded, got %v", hits)
+	}
+
+	// 所有候选节点都失败后才在本地加载
+	if view, _ := gee.Get("Jack"); view.String() != "local" || getterCalls.Load() != 1 {
+		t.Fatalf("expect local fallback after all replicas fail, got %q", view.String())
+	}
+}
+
+func TestGroupIsolation(t *testing.T) {
+	release := make(chan struct{})
+	slow := NewGroup("isolation-slow", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			<-release
+			return []byte(key), nil
+		}))
+	healthy := NewGroup("isolation-healthy", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+
+	// 让慢组的大量请求阻塞在getter中
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			slow.Get(strconv.Itoa(i))
+		}(i)
+	}
+	defer func() {
+		close(release)
+		wg.Wait()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if view, err := healthy.Get(strconv.Itoa(i)); err != nil || view.String() != strconv.Itoa(i) {
+				t.Errorf("healthy group returned %q (err=%v)", view.String(), err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("healthy group was blocked by the slow group")
+	}
+}
+
+func TestReadOnlyRole(t *testing.T) {
+	var getterCalls atomic.Int32
+	gee := NewGroup("read-only", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls.Add(1)
+			return []byte(key), nil
+		}), WithReadOnlyRole())
+
+	// 未注册远程节点时无处加载
+	if _, err := gee.Get("Tom"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expect ErrReadOnly, got %v", err)
+	}
+
+	gee.RegisterPeers(fakePeers{&fakePeer{data: map[string]string{"Tom": "630"}}})
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "630" {
+		t.Fatalf("expect loader peer to serve 630, got %q (err=%v)", view.String(), err)
+	}
+	// 远程获取失败也不回退到本地getter
+	if _, err := gee.Get("Jack"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expect ErrReadOnly, got %v", err)
+	}
+	if getterCalls.Load() != 0 {
+		t.Fatalf("read-only node invoked its getter %d times", getterCalls.Load())
+	}
+}
+
+func TestGetAsync(t *testing.T) {
+	var getterCalls atomic.Int32
+	gee := NewGroup("get-async", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			return []byte("630"), nil
+		}))
+
+	if _, ok := gee.GetAsync("Tom"); ok {
+		t.Fatal("first call should miss")
+	}
+	if _, ok := gee.GetAsync("Tom"); ok {
+		t.Fatal("call during the load should still miss")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if view, ok := gee.GetAsync("Tom"); ok {
+			if view.String() != "630" {
+				t.Fatalf("expect 630, got %q", view.String())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background load never populated the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := getterCalls.Load(); n != 1 {
+		t.Fatalf("background loads should be coalesced, getter ran %d times", n)
+	}
+}
+
+func TestGetAsyncShutdown(t *testing.T) {
+	var getterCalls atomic.Int32
+	release := make(chan struct{})
+	gee := NewGroup("get-async-shutdown", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls.Add(1)
+			<-release
+			return []byte(key), nil
+		}))
+	gee.GetAsync("Tom")
+	for getterCalls.Load() == 0 {
+		runtime.Gosched()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- gee.Shutdown(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("Shutdown should wait for the background load")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := gee.GetAsync("Jack"); ok {
+		t.Fatal("expect a miss after Shutdown")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := getterCalls.Load(); n != 1 {
+		t.Fatalf("GetAsync should not load after Shutdown, getter called %d times", n)
+	}
+}
+
+func TestAdmissionBuffer(t *testing.T) {
+	var getterCalls atomic.Int32
+	gee := NewGroup("admission-buffer", 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls.Add(1)
+			return []byte(key), nil
+		}), WithAdmissionBuffer(8))
+
+	for i := 0; i < 20; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	// 缓冲区中的值在下一次未命中时写入LRU，不会重复加载
+	for i := 0; i < 20; i++ {
+		if view, err := gee.Get(strconv.Itoa(i)); err != nil || view.String() != strconv.Itoa(i) {
+			t.Fatalf("unexpected value %q (err=%v)", view.String(), err)
+		}
+	}
+	if n := getterCalls.Load(); n != 20 {
+		t.Fatalf("expect 20 loads, got %d", n)
+	}
+	if n := gee.Stats().Len; n != 20 {
+		t.Fatalf("expect 20 cached entries, got %d", n)
+	}
+
+	// 命中时不处理缓冲区
+	c := &gee.mainCache
+	c.admit("pending", ByteView{b: []byte("p")}, loadTicket{})
+	if _, ok := c.get("0"); !ok || len(c.pending) != 1 {
+		t.Fatalf("a hit should leave the buffer alone, %d pending", len(c.pending))
+	}
+	// 删除时先写入缓冲区，缓冲区中的缓存项不会在删除之后重新出现
+	if n := c.removeIf([]string{"pending"}, func(string) bool { return true }); n != 1 {
+		t.Fatalf("expect the buffered entry to be removed, removed %d", n)
+	}
+	if _, ok := c.get("pending"); ok {
+		t.Fatal("removed entry came back from the buffer")
+	}
+}
+
+// benchmarkMissStorm 模拟大量不同key同时未命中：每次Get都查询缓存、调用getter并写入缓存
+func benchmarkMissStorm(b *testing.B, opts ...GroupOption) {
+	gee := NewGroup(b.Name(), 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("v"), nil }), opts...)
+	var seq atomic.Int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			gee.Get(strconv.FormatInt(seq.Add(1), 10))
+		}
+	})
+}
+
+func BenchmarkMissStorm(b *testing.B) {
+	benchmarkMissStorm(b)
+}
+
+func BenchmarkMissStormAdmissionBuffer(b *testing.B) {
+	benchmarkMissStorm(b, WithAdmissionBuffer(1024))
+}
+
+func TestGetReader(t *testing.T) {
+	value := strings.Repeat("0123456789", 1000)
+	gee := NewGroup("get-reader", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(value), nil }))
+
+	r, n, err := gee.GetReader("big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n != int64(len(value)) {
+		t.Fatalf("expect length %d, got %d", len(value), n)
+	}
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, r); err != nil || sb.String() != value {
+		t.Fatalf("reader returned %d bytes (err=%v)", sb.Len(), err)
+	}
+
+	if _, _, err := gee.GetReader(""); err == nil {
+		t.Fatal("expect error for empty key")
+	}
+}
+
+// TestGetReaderBufferPool 测试开启缓冲池时读取器在Close之前持有值的内存
+func TestGetReaderBufferPool(t *testing.T) {
+	gee := NewGroup("get-reader-pool", 64, GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(strings.Repeat(key, 10)), nil
+		}), WithBufferPool())
+
+	r, n, err := gee.GetReader("aaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 读取期间缓存项被淘汰，其他值不断加载，若内存被复用读取器会读到其他key的数据
+	for i := 0; i < 100; i++ {
+		gee.Get(fmt.Sprintf("%03d", i))
+	}
+	if _, ok := gee.mainCache.peek("aaa"); ok {
+		t.Fatal("aaa should have been evicted")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || int64(len(data)) != n || string(data) != strings.Repeat("aaa", 10) {
+		t.Fatalf("reader returned %q (err=%v)", data, err)
+	}
+	if len(gee.mainCache.pins) != 1 {
+		t.Fatalf("expect the value to stay pinned until Close, got %d pins", len(gee.mainCache.pins))
+	}
+	r.Close()
+	r.Close()
+	if len(gee.mainCache.pins) != 0 {
+		t.Fatalf("Close should release the pin, got %d pins", len(gee.mainCache.pins))
+	}
+}
+
+// TestBufferPoolOversizeValue 测试超过cacheBytes的值在写入时被淘汰后，
+// 其他key并发加载也不会复用它的内存
+func TestBufferPoolOversizeValue(t *testing.T) {
+	gee := NewGroup("buffer-pool-oversize", 64, GetterFunc(
+		func(key string) ([]byte, error) {
+			return bytes.Repeat([]byte(key[:1]), 1<<10), nil
+		}), WithBufferPool())
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("%c%d", 'a'+w, i)
+				view, err := gee.Get(key)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				runtime.Gosched() // 让其他key的加载有机会复用内存
+				if want := bytes.Repeat([]byte(key[:1]), 1<<10); !bytes.Equal(view.ByteSlice(), want) {
+					t.Errorf("key %s: value overwritten by another load", key)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	r, n, err := gee.GetReader("zzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		gee.Get(fmt.Sprintf("y%d", i))
+	}
+	data, _ := io.ReadAll(r)
+	if int64(len(data)) != n || !bytes.Equal(data, bytes.Repeat([]byte("z"), 1<<10)) {
+		t.Fatal("reader of an oversize value was overwritten by another load")
+	}
+	r.Close()
+	if len(gee.mainCache.pins) != 0 {
+		t.Fatalf("admission holds should be released, got %d pins", len(gee.mainCache.pins))
+	}
+}
+
+// TestLoadHoldSkipsErrors 测试加载失败的结果不在保留窗口内复用
+func TestLoadHoldSkipsErrors(t *testing.T) {
+	var calls atomic.Int32
+	gee := NewGroup("load-hold-errors", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			if calls.Add(1) == 1 {
+				return nil, errors.New("transient backend error")
+			}
+			return []byte("630"), nil
+		}), WithLoadHold(time.Minute))
+
+	if _, err := gee.Get("Tom"); err == nil {
+		t.Fatal("expect the first load to fail")
+	}
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "630" {
+		t.Fatalf("a transient error should not be replayed, got %q (err=%v)", view.String(), err)
+	}
+}
+
+func TestBufferPoolWithLoadHold(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithBufferPool with WithLoadHold should panic")
+		}
+	}()
+	NewGroup("buffer-pool-hold", 64, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithBufferPool(), WithLoadHold(time.Second))
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	var loaded []string
+	gee := NewGroup("key-normalizer", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			loaded = append(loaded, key)
+			return []byte("630"), nil
+		}), WithKeyNormalizer(func(key string) string {
+		return strings.ToLower(strings.TrimSpace(key))
+	}))
+
+	for _, key := range []string{"User:123", "user:123", " user:123 ", "USER:123"} {
+		if view, err := gee.Get(key); err != nil || view.String() != "630" {
+			t.Fatalf("key %q: unexpected value %q (err=%v)", key, view.String(), err)
+		}
+	}
+	if !reflect.DeepEqual(loaded, []string{"user:123"}) {
+		t.Fatalf("expect one load of the normalized key, got %v", loaded)
+	}
+	if _, err := gee.Get("   "); err == nil {
+		t.Fatal("key normalized to empty should be rejected")
+	}
+}
+
+func TestWithoutSingleflight(t *testing.T) {
+	const n = 10
+	concurrentMisses := func(name string, opts ...GroupOption) int32 {
+		var getterCalls atomic.Int32
+		gee := NewGroup(name, 2<<10, GetterFunc(
+			func(key string) ([]byte, error) {
+				getterCalls.Add(1)
+				time.Sleep(50 * time.Millisecond) // 保证所有请求都在加载完成前到达
+				return []byte("v"), nil
+			}), opts...)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if view, err := gee.Get("key"); err != nil || view.String() != "v" {
+					t.Errorf("expect v, got %q (err=%v)", view.String(), err)
+				}
+			}()
+		}
+		wg.Wait()
+		return getterCalls.Load()
+	}
+
+	if calls := concurrentMisses("singleflight-on"); calls != 1 {
+		t.Errorf("expect 1 getter call with singleflight, got %d", calls)
+	}
+	if calls := concurrentMisses("singleflight-off", WithoutSingleflight()); calls != n {
+		t.Errorf("expect %d getter calls without singleflight, got %d", n, calls)
+	}
+}
+
+func TestRemoveGroup(t *testing.T) {
+	mu.RLock()
+	before := len(groups)
+	mu.RUnlock()
+
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				name := fmt.Sprintf("tenant-%d-%d", w, i)
+				g := NewGroup(name, 2<<10, getter)
+				g.Get("key")
+				if GetGroup(name) != g {
+					t.Errorf("group %s should be registered", name)
+				}
+				if !RemoveGroup(name) {
+					t.Errorf("RemoveGroup(%s) should report removal", name)
+				}
+				if GetGroup(name) != nil {
+					t.Errorf("group %s should be removed", name)
+				}
+				if g.mainCache.Len() != 0 {
+					t.Errorf("removed group %s should free its cache", name)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	mu.RLock()
+	after := len(groups)
+	mu.RUnlock()
+	if after != before {
+		t.Errorf("registry leaked groups: %d before, %d after", before, after)
+	}
+	if RemoveGroup("no-such-group") {
+		t.Error("removing an unknown group should return false")
+	}
+}
+
+// TestRemoveGroupLockContention 测试RemoveGroup关闭分组时不持有注册表的锁，
+// 一个分组停止得很慢时，其他分组的创建和查找不受影响
+func TestRemoveGroupLockContention(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	slow := NewGroup("contention-slow", 2<<10, getter)
+	release := make(chan struct{})
+	slow.goBackground(func() {
+		<-slow.closed
+		<-release // 模拟停止得很慢的后台任务
+	})
+	removed := make(chan struct{})
+	go func() {
+		RemoveGroup("contention-slow")
+		close(removed)
+	}()
+	for GetGroup("contention-slow") != nil {
+		runtime.Gosched()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			name := fmt.Sprintf("contention-%d", i)
+			NewGroup(name, 2<<10, getter)
+			if GetGroup(name) == nil {
+				t.Errorf("group %s should be registered", name)
+			}
+			RemoveGroup(name)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("registry blocked while another group was closing")
+	}
+	select {
+	case <-removed:
+		t.Fatal("RemoveGroup should wait for the slow group to stop")
+	default:
+	}
+	close(release)
+	<-removed
+}
+
+// BenchmarkRegistryContention 测量分组频繁创建和删除时GetGroup的开销
+func BenchmarkRegistryContention(b *testing.B) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	NewGroup("registry-hot", 2<<10, getter)
+	defer RemoveGroup("registry-hot")
+	var seq atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%16 == 0 { // 每16次查找创建并删除一个短生命周期的分组
+				name := "registry-churn-" + strconv.FormatInt(seq.Add(1), 10)
+				NewGroup(name, 2<<10, getter)
+				RemoveGroup(name)
+				continue
+			}
+			if GetGroup("registry-hot") == nil {
+				b.Fatal("hot group missing")
+			}
+		}
+	})
+}
+
+func TestRemoveGroupStopsGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	for i := 0; i < 20; i++ {
+		g := NewGroup(fmt.Sprintf("leak-%d", i), 2<<10, getter)
+		g.StartConsistencyCheck(time.Millisecond, 1, nil)
+		g.StartConsistencyCheck(time.Millisecond, 1, nil)
+	}
+	if runtime.NumGoroutine() < before+40 {
+		t.Fatalf("expect background checkers to be running")
+	}
+	for i := 0; i < 20; i++ {
+		RemoveGroup(fmt.Sprintf("leak-%d", i))
+	}
+
+	// Close等待后台协程退出，删除后协程数应立即恢复
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked: %d before, %d after RemoveGroup", before, after)
+	}
+
+	// 已关闭的分组不再启动后台任务
+	closed := NewGroup("leak-closed", 2<<10, getter)
+	closed.Close()
+	stop := closed.StartConsistencyCheck(time.Millisecond, 1, nil)
+	stop()
+	closed.Close() // 重复关闭是安全的
+}
+
+func TestMemoryOverheadEstimate(t *testing.T) {
+	gee := NewGroup("overhead", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	if n := gee.MemoryOverheadEstimate(); n != 0 {
+		t.Fatalf("empty cache should have no overhead, got %d", n)
+	}
+
+	for i := 0; i < 10; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	small := gee.MemoryOverheadEstimate()
+	for i := 10; i < 100; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	large := gee.MemoryOverheadEstimate()
+	if small <= 0 || large != 10*small {
+		t.Errorf("overhead should scale with entry count, got %d for 10 and %d for 100 entries", small, large)
+	}
+	// ByteView保存在LRU条目中，不应在LRU的估算之外重复计入
+	if want := gee.mainCache.lru.OverheadEstimate(); large != want {
+		t.Errorf("overhead should equal the LRU estimate %d, got %d", want, large)
+	}
+}
+
+// TestValueDedupOverwrite 测试开启去重后覆盖写入不计入LRU的命中统计
+func TestValueDedupOverwrite(t *testing.T) {
+	gee := NewGroup("dedup-overwrite", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }), WithValueDedup())
+	for i := 0; i < 3; i++ {
+		gee.populateCache("k", ByteView{b: []byte(fmt.Sprintf("v%d", i))})
+	}
+	if hits, _ := gee.mainCache.lru.Stats(); hits != 0 {
+		t.Fatalf("overwrites should not count as LRU hits, got %d", hits)
+	}
+	if len(gee.mainCache.dedup) != 1 {
+		t.Fatalf("overwritten values should be released, %d shared values left", len(gee.mainCache.dedup))
+	}
+}
+
+func TestValueDedup(t *testing.T) {
+	avatar := bytes.Repeat([]byte("default-avatar"), 10)
+	gee := NewGroup("dedup", 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			if strings.HasPrefix(key, "custom") {
+				return []byte(key), nil
+			}
+			return avatar, nil
+		}), WithValueDedup())
+
+	var views []ByteView
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user%d", i)
+		if _, err := gee.Get(key); err != nil {
+			t.Fatal(err)
+		}
+		view, _ := gee.mainCache.get(key)
+		views = append(views, view)
+	}
+	gee.Get("custom1")
+	for _, v := range views[1:] {
+		if &v.b[0] != &views[0].b[0] {
+			t.Fatal("identical values should share one underlying storage")
+		}
+	}
+	gee.mainCache.mu.Lock()
+	blobs, refs := len(gee.mainCache.dedup), gee.mainCache.dedup[contentHash(avatar)].refs
+	gee.mainCache.mu.Unlock()
+	if blobs != 2 || refs != 100 {
+		t.Fatalf("expect 2 stored blobs with 100 refs to the shared one, got %d blobs and %d refs", blobs, refs)
+	}
+
+	// 淘汰所有引用后共享数据被释放
+	for gee.mainCache.Len() > 0 {
+		gee.mainCache.evictOldest()
+	}
+	if n := len(gee.mainCache.dedup); n != 0 {
+		t.Fatalf("blobs should be freed after eviction, %d left", n)
+	}
+}
+
+func TestLockWatchdog(t *testing.T) {
+	fired := make(chan time.Duration, 1)
+	gee := NewGroup("lock-watchdog", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithLockWatchdog(20*time.Millisecond, func(group string, held time.Duration) {
+			fired <- held
+		}))
+	defer RemoveGroup("lock-watchdog")
+
+	// 正常的短时间加锁不会触发
+	gee.Get("a")
+	select {
+	case <-fired:
+		t.Fatal("watchdog should not fire for short critical sections")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 模拟长时间持有的锁
+	gee.mainCache.lock()
+	select {
+	case held := <-fired:
+		if held < 20*time.Millisecond {
+			t.Errorf("expect held >= threshold, got %v", held)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchdog should fire for a long-held lock")
+	}
+	gee.mainCache.unlock()
+}
+
+func TestRejectEmptyValues(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return []byte{}, nil // 后端用空值表示不存在
+		}
+		return []byte(key), nil
+	})
+
+	strict := NewGroup("reject-empty", 2<<10, getter, WithRejectEmptyValues())
+	if _, err := strict.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect ErrNotFound, got %v", err)
+	}
+	if _, ok := strict.mainCache.get("missing"); ok {
+		t.Fatal("empty value should not be cached")
+	}
+	if view, err := strict.Get("Tom"); err != nil || view.String() != "Tom" {
+		t.Fatalf("non-empty values should load normally, got %q (err=%v)", view.String(), err)
+	}
+
+	// 默认允许空值
+	lenient := NewGroup("allow-empty", 2<<10, getter)
+	if view, err := lenient.Get("missing"); err != nil || view.Len() != 0 {
+		t.Fatalf("expect empty value by default, got %q (err=%v)", view.String(), err)
+	}
+	if _, ok := lenient.mainCache.get("missing"); !ok {
+		t.Fatal("empty value should be cached by default")
+	}
+}
+
+func TestValueIndexer(t *testing.T) {
+	owners := map[string]string{"order1": "alice", "order2": "bob", "order3": "alice"}
+	gee := NewGroup("value-index", 0, GetterFunc(
+		func(key string) ([]byte, error) {
+			return json.Marshal(map[string]string{"id": key, "owner": owners[key]})
+		}),
+		WithValueIndexer(func(key string, v ByteView) []string {
+			var order struct{ Owner string }
+			if json.Unmarshal(v.ByteSlice(), &order) != nil {
+				return nil
+			}
+			return []string{"owner:" + order.Owner}
+		}))
+
+	for key := range owners {
+		gee.Get(key)
+	}
+	if keys := gee.KeysByIndex("owner:alice"); strings.Join(keys, ",") != "order1,order3" {
+		t.Fatalf("expect [order1 order3], got %v", keys)
+	}
+
+	// 覆盖后旧的索引词被删除
+	gee.populateCache("order1", ByteView{b: []byte(`{"owner":"bob"}`)})
+	if keys := gee.KeysByIndex("owner:bob"); strings.Join(keys, ",") != "order1,order2" {
+		t.Fatalf("expect [order1 order2], got %v", keys)
+	}
+	if keys := gee.KeysByIndex("owner:alice"); strings.Join(keys, ",") != "order3" {
+		t.Fatalf("expect [order3], got %v", keys)
+	}
+
+	// 淘汰后索引同步删除
+	for gee.mainCache.Len() > 0 {
+		gee.mainCache.evictOldest()
+	}
+	if keys := gee.KeysByIndex("owner:bob"); len(keys) != 0 {
+		t.Fatalf("evicted keys should leave the index, got %v", keys)
+	}
+}
+
+func TestInvalidateTag(t *testing.T) {
+	gee := NewGroup("invalidate-tag", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("loaded-" + key), nil }))
+	defer gee.Close()
+
+	gee.SetWithTags("report1", []byte("r1"), 0, "user:1", "product:9")
+	gee.SetWithTags("report2", []byte("r2"), 0, "user:2", "product:9")
+	gee.SetWithTags("report3", []byte("r3"), 0, "user:1")
+	if err := gee.SetWithTags("", []byte("x"), 0, "user:1"); err == nil {
+		t.Fatal("expect error for an empty key")
+	}
+
+	// 删除带有共享标签的所有缓存项，其他缓存项保留
+	if n := gee.InvalidateTag("product:9"); n != 2 {
+		t.Fatalf("expect 2 keys invalidated, got %d", n)
+	}
+	for key, cached := range map[string]bool{"report1": false, "report2": false, "report3": true} {
+		if _, ok := gee.mainCache.get(key); ok != cached {
+			t.Fatalf("key %s: expect cached=%v", key, cached)
+		}
+	}
+	// 被删除的缓存项的其他标签也随之删除
+	if keys := gee.mainCache.tags.lookup("user:1"); strings.Join(keys, ",") != "report3" {
+		t.Fatalf("expect [report3] under user:1, got %v", keys)
+	}
+
+	// 重新写入（包括加载）替换原有的标签
+	gee.populateCache("report3", ByteView{b: []byte("r3-v2")})
+	if n := gee.InvalidateTag("user:1"); n != 0 {
+		t.Fatalf("overwritten key should lose its tags, invalidated %d", n)
+	}
+
+	// 过期和淘汰的缓存项离开标签索引
+	gee.SetWithTags("short", []byte("s"), 10*time.Millisecond, "t")
+	time.Sleep(20 * time.Millisecond)
+	if view, _ := gee.Get("short"); view.String() != "loaded-short" {
+		t.Fatalf("expired tagged entry should reload, got %q", view.String())
+	}
+	for gee.mainCache.Len() > 0 {
+		gee.mainCache.evictOldest()
+	}
+	if len(gee.mainCache.tags.terms) != 0 || len(gee.mainCache.tags.byKey) != 0 {
+		t.Fatalf("tag index should be empty after eviction, got %v", gee.mainCache.tags.terms)
+	}
+}
+
+// TestDeleteDuringLoad 测试加载期间删除键时，加载的旧值不会写入缓存
+func TestDeleteDuringLoad(t *testing.T) {
+	for _, opts := range [][]GroupOption{nil, {WithAdmissionBuffer(8)}} {
+		var version atomic.Int32
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		gee := NewGroup(fmt.Sprintf("delete-during-load-%d", len(opts)), 0, GetterFunc(
+			func(key string) ([]byte, error) {
+				v := version.Load()
+				if v == 0 {
+					started <- struct{}{}
+					<-release
+				}
+				return []byte(fmt.Sprintf("v%d", v)), nil
+			}), opts...)
+
+		got := make(chan string)
+		go func() {
+			view, _ := gee.Get("k")
+			got <- view.String()
+		}()
+		<-started
+		// 源数据在加载期间发生变化并删除缓存
+		version.Store(1)
+		if gee.Delete("k") {
+			t.Fatal("key being loaded should not be cached yet")
+		}
+		close(release)
+		if v := <-got; v != "v0" {
+			t.Fatalf("the in-flight Get should still return its value, got %q", v)
+		}
+		gee.mainCache.drain(nil)
+		if _, ok := gee.mainCache.get("k"); ok {
+			t.Fatal("stale value loaded before the delete should not be cached")
+		}
+		if view, _ := gee.Get("k"); view.String() != "v1" {
+			t.Fatalf("expect the fresh value after the delete, got %q", view.String())
+		}
+		if len(gee.mainCache.loading) != 0 {
+			t.Fatalf("finished loads should leave no generation records, got %d", len(gee.mainCache.loading))
+		}
+		gee.Close()
+	}
+}
+
+func TestPeerErrorLogRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	gee := NewGroup("peer-error-log", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithPeerErrorLogInterval(50*time.Millisecond))
+	// 模拟宕机的节点：监听地址已关闭，每个key的错误信息都包含各自的URL
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	gee.RegisterPeers(fakePeers{&httpGetter{baseURL: "http://" + ln.Addr().String() + defaultBasePath}})
+
+	count := func() int { return strings.Count(buf.String(), "Failed to get from peer") }
+	for i := 0; i < 100; i++ {
+		gee.Get(fmt.Sprintf("key%d", i))
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expect 1 log line for 100 failures of the same peer, got %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	gee.Get("key-after-interval")
+	if n := count(); n != 2 {
+		t.Fatalf("expect a second log line after the interval, got %d", n)
+	}
+	if !strings.Contains(buf.String(), "repeated 99 times") {
+		t.Fatalf("expect the suppressed count to be reported, got:\n%s", buf.String())
+	}
+}
+
+// TestSnapshotKeepsOrder 测试快照不改变访问顺序，也不计入LRU的命中统计
+func TestSnapshotKeepsOrder(t *testing.T) {
+	gee := NewGroup("snapshot-order", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	for _, key := range []string{"a", "b", "c"} {
+		gee.Get(key)
+	}
+	gee.Get("a") // 访问顺序从旧到新为 b, c, a
+	hits, _ := gee.mainCache.lru.Stats()
+
+	if snap := gee.Snapshot(); len(snap) != 3 {
+		t.Fatalf("expect 3 entries, got %d", len(snap))
+	}
+	if got, want := gee.OldestKeys(3), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshot should not reorder the LRU, got %v want %v", got, want)
+	}
+	if after, _ := gee.mainCache.lru.Stats(); after != hits {
+		t.Fatalf("snapshot should not count LRU hits, %d -> %d", hits, after)
+	}
+}
+
+func TestSnapshotConcurrentWrites(t *testing.T) {
+	gee := NewGroup("snapshot", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key + "=0"), nil }))
+	for i := 0; i < 1000; i++ {
+		gee.Get(fmt.Sprintf("key%d", i))
+	}
+
+	// 后台持续拍摄快照
+	stop := make(chan struct{})
+	snapshotDone := make(chan struct{})
+	go func() {
+		defer close(snapshotDone)
+		for {
+			snap := gee.Snapshot()
+			if len(snap) != 1000 {
+				t.Errorf("expect 1000 entries, got %d", len(snap))
+			}
+			// 每个值都是该键某一时刻的完整值
+			for key, v := range snap {
+				if !strings.HasPrefix(v.String(), key+"=") {
+					t.Errorf("corrupted value %q for key %s", v.String(), key)
+					return
+				}
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	// 快照期间写入方不被阻塞，能够在限定时间内完成
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 1; n <= 2000; n++ {
+				key := fmt.Sprintf("key%d", (n*7+w)%1000)
+				gee.populateCache(key, ByteView{b: []byte(fmt.Sprintf("%s=%d", key, n))})
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("writers stalled while snapshots were taken")
+	}
+	close(stop)
+	<-snapshotDone
+}
+
+func TestAccessStats(t *testing.T) {
+	gee := NewGroup("access-stats", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }), WithAccessStats())
+
+	hits := map[string]int{"a": 5, "b": 3, "c": 3, "d": 1}
+	start := time.Now()
+	for key, n := range hits {
+		for i := 0; i <= n; i++ { // 第一次未命中并加载，之后n次命中
+			gee.Get(key)
+		}
+	}
+
+	stats := gee.AccessStats(3)
+	var got []string
+	for _, st := range stats {
+		got = append(got, fmt.Sprintf("%s=%d", st.Key, st.Count))
+		if st.LastAccess.Before(start) {
+			t.Errorf("%s: last access %v should be recorded", st.Key, st.LastAccess)
+		}
+	}
+	if strings.Join(got, ",") != "a=5,b=3,c=3" {
+		t.Fatalf("expect [a=5 b=3 c=3], got %v", got)
+	}
+
+	// 只统计当前在缓存中的缓存项
+	for gee.mainCache.Len() > 0 {
+		gee.mainCache.evictOldest()
+	}
+	if stats := gee.AccessStats(10); len(stats) != 0 {
+		t.Fatalf("evicted keys should be dropped from stats, got %v", stats)
+	}
+}
+
+func TestShutdownDrainsLoads(t *testing.T) {
+	started := make(chan struct{})
+	gee := NewGroup("shutdown", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return []byte(key), nil
+		}))
+	defer RemoveGroup("shutdown")
+
+	result := make(chan error, 1)
+	go func() {
+		view, err := gee.Get("slow")
+		if err == nil && view.String() != "slow" {
+			err = fmt.Errorf("unexpected value %q", view.String())
+		}
+		result <- err
+	}()
+	<-started
+
+	if err := gee.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	// 进行中的加载在Shutdown返回前完成
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("in-flight load should complete, got %v", err)
+		}
+	default:
+		t.Fatal("Shutdown returned before the in-flight load finished")
+	}
+	if _, err := gee.Get("new"); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("expect ErrShutdown for new loads, got %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	gee := NewGroup("shutdown-timeout", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			close(started)
+			<-release
+			return []byte(key), nil
+		}))
+	defer RemoveGroup("shutdown-timeout")
+	defer close(release)
+
+	go gee.Get("stuck")
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := gee.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestMissFallback(t *testing.T) {
+	primary := NewGroup("fallback-primary", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return nil, fmt.Errorf("%s not exist", key) }))
+	experimental := NewGroup("fallback-experimental", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("exp-" + key), nil }))
+
+	if _, err := primary.Get("Tom"); err == nil {
+		t.Fatal("expect a miss without fallback")
+	}
+	if err := primary.SetMissFallback(experimental); err != nil {
+		t.Fatal(err)
+	}
+	if view, err := primary.Get("Tom"); err != nil || view.String() != "exp-Tom" {
+		t.Fatalf("expect fallback to serve exp-Tom, got %q (err=%v)", view.String(), err)
+	}
+	if _, ok := primary.mainCache.get("Tom"); ok {
+		t.Fatal("fallback values should not be cached in the primary group")
+	}
+
+	// 形成环的设置被拒绝
+	if err := experimental.SetMissFallback(primary); err == nil {
+		t.Fatal("expect an error for a fallback cycle")
+	}
+	if err := primary.SetMissFallback(primary); err == nil {
+		t.Fatal("expect an error for a self fallback")
+	}
+}
+
+func TestValuePreviewInLogs(t *testing.T) {
+	tests := []struct {
+		value  string
+		max    int
+		expect string
+	}{
+		{"630", 8, `"630"`},
+		{"0123456789", 4, `"0123"... (10 bytes)`},
+		{"a\x00\xff\n\"b", 16, `"a\x00\xff\x0a\"b"`},
+	}
+	for _, tt := range tests {
+		if got := previewValue(ByteView{b: []byte(tt.value)}, tt.max); got != tt.expect {
+			t.Errorf("previewValue(%q, %d) = %s, expect %s", tt.value, tt.max, got, tt.expect)
+		}
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte("secret-token"), nil })
+
+	quiet := NewGroup("preview-off", 2<<10, getter)
+	quiet.Get("k")
+	quiet.Get("k")
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("values should not be logged by default, got:\n%s", buf.String())
+	}
+
+	verbose := NewGroup("preview-on", 2<<10, getter, WithValuePreviewInLogs(6))
+	verbose.Get("k")
+	verbose.Get("k")
+	if !strings.Contains(buf.String(), `hit k: "secret"... (12 bytes)`) {
+		t.Fatalf("expect a truncated preview in the hit log, got:\n%s", buf.String())
+	}
+}
+
+func TestZeroCacheBytes(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// 0表示不限制内存：所有加载的值都被保留，并在创建时给出警告
+	gee := NewGroup("zero-bytes", 0, GetterFunc(
+		func(key string) ([]byte, error) { return bytes.Repeat([]byte("x"), 1<<10), nil }))
+	if !strings.Contains(buf.String(), "zero-bytes has no memory limit") {
+		t.Fatalf("expect a warning for cacheBytes=0, got:\n%s", buf.String())
+	}
+	for i := 0; i < 100; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	if n := gee.mainCache.Len(); n != 100 {
+		t.Fatalf("cacheBytes=0 should be unbounded, kept %d of 100 entries", n)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("negative cacheBytes should panic")
+		}
+	}()
+	NewGroup("negative-bytes", -1, GetterFunc(
+		func(key string) ([]byte, error) { return nil, nil }))
+}
+
+// fakeSink 记录每次推送的统计快照
+type fakeSink struct {
+	mu      sync.Mutex
+	flushes []Stats
+}
+
+func (s *fakeSink) Flush(group string, stats Stats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes = append(s.flushes, stats)
+	return nil
+}
+
+func (s *fakeSink) snapshot() []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Stats(nil), s.flushes...)
+}
+
+func TestMetricsSink(t *testing.T) {
+	sink := &fakeSink{}
+	gee := NewGroup("metrics-sink", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }),
+		WithMetricsSink(sink, 10*time.Millisecond))
+	defer RemoveGroup("metrics-sink")
+
+	gee.Get("a") // 未命中
+	gee.Get("a") // 命中
+	gee.Get("a") // 命中
+	time.Sleep(50 * time.Millisecond)
+
+	flushes := sink.snapshot()
+	if len(flushes) < 2 {
+		t.Fatalf("expect periodic flushes, got %d", len(flushes))
+	}
+	if last := flushes[len(flushes)-1]; last.Hits != 2 || last.Misses != 1 || last.Len != 1 {
+		t.Fatalf("expect 2 hits, 1 miss and 1 entry, got %+v", last)
+	}
+
+	// Close时再推送最后一次
+	gee.Get("b")
+	gee.Close()
+	flushes = sink.snapshot()
+	if last := flushes[len(flushes)-1]; last.Misses != 2 {
+		t.Fatalf("expect a final flush with 2 misses on Close, got %+v", last)
+	}
+	n := len(flushes)
+	time.Sleep(30 * time.Millisecond)
+	if len(sink.snapshot()) != n {
+		t.Fatal("flusher should stop after Close")
+	}
+}
+
+// TestMissFallbackConcurrentCycle 测试并发地互相设置后备分组时不会形成环
+func TestMissFallbackConcurrentCycle(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return nil, ErrNotFound })
+	for i := 0; i < 200; i++ {
+		a := NewGroup("fallback-race-a", 2<<10, getter)
+		b := NewGroup("fallback-race-b", 2<<10, getter)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); a.SetMissFallback(b) }()
+		go func() { defer wg.Done(); b.SetMissFallback(a) }()
+		wg.Wait()
+		if a.fallback.Load() == b && b.fallback.Load() == a {
+			t.Fatal("concurrent SetMissFallback formed a cycle")
+		}
+	}
+	RemoveGroup("fallback-race-a")
+	RemoveGroup("fallback-race-b")
+}
+
+func TestNegativeCache(t *testing.T) {
+	var calls atomic.Int32
+	gee := NewGroup("negative-cache", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			calls.Add(1)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}), WithNegativeCache(50*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		if _, err := gee.Get("ghost"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expect ErrNotFound, got %v", err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("negative entry should absorb repeated misses, getter called %d times", n)
+	}
+
+	// 负缓存按自己的过期时间失效
+	time.Sleep(60 * time.Millisecond)
+	gee.Get("ghost")
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expired negative entry should reload, getter called %d times", n)
+	}
+
+	// 写入值立即清除负缓存记录
+	fill := gee.BeginFill("ghost")
+	fill.Write([]byte("found"))
+	if err := fill.Commit(0); err != nil {
+		t.Fatal(err)
+	}
+	if view, err := gee.Get("ghost"); err != nil || view.String() != "found" {
+		t.Fatalf("expect committed value, got %q (err=%v)", view.String(), err)
+	}
+	if err := gee.negative.get("ghost"); err != nil {
+		t.Fatalf("commit should clear the negative entry, got %v", err)
+	}
+}
+
+// TestNegativeCacheWithFallback 测试负缓存命中时仍查询后备分组，并记录耗时
+// TestNegativeCacheJanitor 测试开启负缓存的分组注册到共享清理器，关闭时注销
+func TestNegativeCacheJanitor(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	})
+	a := NewGroup("janitor-a", 2<<10, getter, WithNegativeCache(time.Millisecond))
+	b := NewGroup("janitor-b", 2<<10, getter, WithNegativeCache(time.Millisecond))
+	plain := NewGroup("janitor-plain", 2<<10, getter)
+	defer RemoveGroup("janitor-plain")
+	if a.janitor == nil || a.janitor != b.janitor || plain.janitor != nil {
+		t.Fatal("groups with a negative cache should share the registry janitor")
+	}
+	j := a.janitor
+	n := j.Len()
+
+	// 清理器通过EvictExpired删除过期的负缓存记录
+	a.Get("ghost")
+	time.Sleep(5 * time.Millisecond)
+	if evicted := a.negative.EvictExpired(); evicted != 1 {
+		t.Fatalf("expect the expired negative entry to be swept, got %d", evicted)
+	}
+
+	RemoveGroup("janitor-a")
+	if j.Len() != n-1 {
+		t.Fatalf("closing a group should unregister it from the janitor, got %d of %d", j.Len(), n)
+	}
+	b.Close()
+	b.Close()
+	if j.Len() != n-2 {
+		t.Fatalf("expect %d caches left in the janitor, got %d", n-2, j.Len())
+	}
+	RemoveGroup("janitor-b")
+}
+
+func TestNegativeCacheWithFallback(t *testing.T) {
+	var calls atomic.Int32
+	primary := NewGroup("negative-fallback-primary", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			calls.Add(1)
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}), WithNegativeCache(time.Minute))
+	experimental := NewGroup("negative-fallback-experimental", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("exp-" + key), nil }))
+	if err := primary.SetMissFallback(experimental); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		view, tm, err := primary.GetTimed("ghost")
+		if err != nil || view.String() != "exp-ghost" {
+			t.Fatalf("round %d: expect fallback to serve exp-ghost, got %q (err=%v)", i, view.String(), err)
+		}
+		if tm.Wait == 0 {
+			t.Fatalf("round %d: expect the load to be timed, got %+v", i, tm)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("negative entry should still absorb getter calls, called %d times", n)
+	}
+}
+
+func TestCapacityMisses(t *testing.T) {
+	gee := NewGroup("capacity-misses", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+
+	gee.Get("k1") // 冷未命中
+	gee.Get("k2") // 冷未命中
+	gee.mainCache.evictOldest()
+	gee.Get("k1") // 被淘汰后再次请求：容量未命中
+	gee.Get("k1") // 命中
+	gee.Get("k3") // 冷未命中
+
+	st := gee.Stats()
+	if st.Misses != 4 || st.CapacityMisses != 1 || st.ColdMisses != 3 {
+		t.Fatalf("expect 4 misses (1 capacity, 3 cold), got %d (%d capacity, %d cold)",
+			st.Misses, st.CapacityMisses, st.ColdMisses)
+	}
+
+	// 显式删除不算容量淘汰
+	gee.mainCache.clear()
+	gee.Get("k1")
+	if st := gee.Stats(); st.CapacityMisses != 1 {
+		t.Fatalf("misses after clear should be cold, got %d capacity misses", st.CapacityMisses)
+	}
+}
+
+func TestTenantQuotas(t *testing.T) {
+	gee := NewGroup("tenant-quotas", 0, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("value"), nil }),
+		WithTenantQuotas(":", map[string]int64{"noisy": 40}, 0))
+
+	for _, key := range []string{"quiet:1", "quiet:2", "quiet:3"} {
+		gee.Get(key)
+	}
+	// noisy的每个缓存项13字节，配额只能容纳3个
+	for i := 0; i < 100; i++ {
+		gee.Get(fmt.Sprintf("noisy:%02d", i))
+	}
+
+	for _, key := range []string{"quiet:1", "quiet:2", "quiet:3"} {
+		if _, ok := gee.mainCache.peek(key); !ok {
+			t.Fatalf("another tenant's flood should not evict %s", key)
+		}
+	}
+	for _, key := range []string{"noisy:97", "noisy:98", "noisy:99"} {
+		if _, ok := gee.mainCache.peek(key); !ok {
+			t.Fatalf("expect the newest entries of the flooding tenant to stay, missing %s", key)
+		}
+	}
+
+	stats := gee.TenantStats()
+	if st := stats["noisy"]; st.Len != 3 || st.Bytes != 39 || st.Quota != 40 || st.Evictions != 97 {
+		t.Fatalf("unexpected noisy tenant stats: %+v", st)
+	}
+	if st := stats["quiet"]; st.Len != 3 || st.Bytes != 36 || st.Quota != 0 || st.Evictions != 0 {
+		t.Fatalf("unexpected quiet tenant stats: %+v", st)
+	}
+	if st := gee.Stats(); st.Len != 6 || st.Evictions != 97 {
+		t.Fatalf("expect 6 entries and 97 evictions in the group, got %d and %d", st.Len, st.Evictions)
+	}
+
+	// 被配额淘汰的键再次请求计为容量未命中
+	gee.Get("noisy:00")
+	if st := gee.Stats(); st.CapacityMisses != 1 {
+		t.Fatalf("expect quota eviction to count as a capacity miss, got %d", st.CapacityMisses)
+	}
+}
+
+func TestMigratePolicy(t *testing.T) {
+	var loads atomic.Int64
+	gee := NewGroup("migrate-policy", 1<<20, GetterFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			return []byte("v-" + key), nil
+		}))
+	defer gee.Close()
+	const n = 1000
+	for i := 0; i < n; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+
+	slru := lru.NewSLRU(1<<20, 0, nil)
+	if err := gee.MigratePolicy(slru); err != nil {
+		t.Fatal(err)
+	}
+	// 迁移期间读取已有的key和写入新key
+	for i := 0; i < n+100; i++ {
+		if v, err := gee.Get(strconv.Itoa(i)); err != nil || v.String() != "v-"+strconv.Itoa(i) {
+			t.Fatalf("key %d: got %q, %v", i, v.String(), err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for gee.mainCache.unmigrated() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d entries left in the old policy", gee.mainCache.unmigrated())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := loads.Load(); got != n+100 {
+		t.Fatalf("expect no data lost during migration, getter called %d times", got)
+	}
+	if slru.Len() != n+100 || gee.mainCache.Len() != n+100 {
+		t.Fatalf("expect %d entries in the new policy, got %d (cache %d)", n+100, slru.Len(), gee.mainCache.Len())
+	}
+	if err := gee.MigratePolicy(lru.NewLFU(0, nil)); err == nil {
+		t.Fatal("expect a second migration to fail")
+	}
+}
+
+// TestMigratePolicyARC 测试迁移到ARC后淘汰统计、快照、热点键和全局内存上限的淘汰覆盖新策略中的缓存项
+func TestMigratePolicyARC(t *testing.T) {
+	gee := NewGroup("migrate-policy-arc", 1<<20, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("v-" + key), nil }))
+	defer gee.Close()
+	for i := 0; i < 10; i++ {
+		gee.Get(strconv.Itoa(i))
+	}
+	arc := lru.NewARCPolicy(8)
+	if err := gee.MigratePolicy(arc); err != nil {
+		t.Fatal(err)
+	}
+	for gee.mainCache.unmigrated() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// ARC最多保存8个缓存项，迁移时淘汰的缓存项计入统计
+	if arc.Len() != 8 || gee.mainCache.evicted() != 2 {
+		t.Fatalf("expect 8 entries and 2 evictions, got %d and %d", arc.Len(), gee.mainCache.evicted())
+	}
+	if snap := gee.Snapshot(); len(snap) != 8 || snap["9"].String() != "v-9" {
+		t.Fatalf("snapshot should cover the migrated entries, got %d", len(snap))
+	}
+	if keys := gee.mainCache.newestN(3); len(keys) != 3 {
+		t.Fatalf("hot keys should cover the migrated entries, got %v", keys)
+	}
+	hits := arc.ARC().Stats().Hits
+	if _, ok := gee.mainCache.peek("9"); !ok || arc.ARC().Stats().Hits != hits {
+		t.Fatal("peek should find the migrated entry without counting an access")
+	}
+	gee.mainCache.evictOldest()
+	if arc.Len() != 7 || gee.mainCache.bytes() != arc.Bytes() {
+		t.Fatalf("evictOldest should evict from the new policy, got %d entries", arc.Len())
+	}
+}
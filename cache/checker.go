@@ -0,0 +1,95 @@
+// checker.go 实现了跨节点的一致性抽样检查
+// 定期从本地缓存随机抽取若干键，向其归属节点请求数据并与本地副本比较，
+// 用于发现漏掉的失效等导致节点间数据不一致的问题。这是一个低频的诊断工具，默认不开启。
+package gocachex
+
+import (
+	"bytes"
+	"context"
+	pb "goCacheX/gocacheXpb"
+	"log"
+	"sync"
+	"time"
+)
+
+// MismatchFunc 在本地副本与归属节点的数据不一致时被调用
+type MismatchFunc func(key string, local, remote ByteView)
+
+// StartConsistencyCheck 启动后台一致性检查
+// 每隔interval从本地缓存随机抽取最多sampleSize个键，对于归属其他节点的键，
+// 向归属节点请求数据并与本地副本比较，不一致时调用onMismatch。
+// 返回的stop函数用于停止检查并等待后台协程退出，可以重复和并发调用；Group.Close也会停止检查。
+func (g *Group) StartConsistencyCheck(interval time.Duration, sampleSize int, onMismatch MismatchFunc) (stop func()) {
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	started := g.goBackground(func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.checkConsistency(sampleSize, onMismatch)
+			case <-stopCh:
+				return
+			case <-g.closed:
+				return
+			}
+		}
+	})
+	if !started { // 分组已关闭，不再启动后台任务
+		close(done)
+	}
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() { close(stopCh) })
+		<-done
+	}
+}
+
+// checkConsistency 执行一轮抽样检查，返回发现的不一致数量
+func (g *Group) checkConsistency(sampleSize int, onMismatch MismatchFunc) int {
+	if g.peers == nil {
+		return 0
+	}
+	mismatches := 0
+	for _, key := range g.mainCache.sampleKeys(sampleSize) {
+		local, ok := g.mainCache.get(key)
+		if !ok {
+			continue // 抽样之后被淘汰
+		}
+		peer, ok := g.peers.PickPeer(key)
+		if !ok {
+			continue // 键归属本节点，没有可比较的远程副本
+		}
+		// 支持条件获取的节点在数据一致时不会重新发送整个值
+		if cp, ok := peer.(ConditionalPeerGetter); ok {
+			res := &pb.Response{}
+			modified, err := cp.GetIfModified(&pb.Request{Group: g.name, Key: key}, res, local.Version())
+			if err != nil {
+				log.Println("[GeeCache] consistency check: failed to get from peer", err)
+				continue
+			}
+			if modified {
+				mismatches++
+				if onMismatch != nil {
+					onMismatch(key, local, ByteView{b: res.Value})
+				}
+			}
+			continue
+		}
+		remote, err := g.getFromPeer(context.Background(), peer, key)
+		if err != nil {
+			log.Println("[GeeCache] consistency check: failed to get from peer", err)
+			continue
+		}
+		if !bytes.Equal(local.b, remote.b) {
+			mismatches++
+			if onMismatch != nil {
+				onMismatch(key, local, remote)
+			}
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,100 @@
+// client.go 实现了一个感知一致性哈希的客户端
+// 客户端在本地构建与服务端相同的哈希环，直接向key的归属节点发起请求，
+// 省去经过任意节点转发的一跳；归属节点不可用时按哈希环顺序转移到下一个节点
+package gocachex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"goCacheX/consistenthash"
+	pb "goCacheX/gocacheXpb"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client 是无需运行缓存节点即可直接查询集群的客户端
+type Client struct {
+	ring    *consistenthash.Map    // 与服务端一致的哈希环
+	getters map[string]*httpGetter // 节点到httpGetter的映射
+}
+
+// NewClient 根据节点列表和虚拟节点倍数创建客户端，replicas不大于0时返回错误
+// peers和replicas必须与服务端HTTPPool使用的配置一致，例如 "http://localhost:8001"。
+// 服务端设置了哈希函数、盐值或虚拟节点格式时，应改用NewClientFromRing导入HTTPPool.ExportRing的结果
+func NewClient(peers []string, replicas int) (*Client, error) {
+	ring := consistenthash.NewMap(replicas, nil)
+	ring.Add(peers...)
+	return NewClientFromRing(ring.Export())
+}
+
+// NewClientFromRing 根据服务端导出的哈希环创建客户端，见HTTPPool.ExportRing
+func NewClientFromRing(data []byte) (*Client, error) {
+	ring, err := consistenthash.ImportMap(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		ring:    ring,
+		getters: make(map[string]*httpGetter),
+	}
+	for _, peer := range ring.Nodes() {
+		c.getters[peer] = &httpGetter{baseURL: peer + defaultBasePath}
+	}
+	return c, nil
+}
+
+// Get 从集群中获取group下key对应的值
+// 先请求key的归属节点，失败后按哈希环顺序依次尝试其他节点，直到成功或ctx结束
+func (c *Client) Get(ctx context.Context, group, key string) ([]byte, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	peers := c.ring.GetN(key, len(c.getters))
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("gocachex: client has no peers")
+	}
+
+	var errs []string
+	for _, peer := range peers {
+		res := &pb.Response{}
+		_, err := c.getters[peer].get(ctx, &pb.Request{Group: group, Key: key}, res, "")
+		if err == nil {
+			return res.Value, nil
+		}
+		if ctx.Err() != nil {
+			return nil, contextError(key, ctx.Err())
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", peer, err))
+	}
+	return nil, fmt.Errorf("gocachex: all peers failed: %s", strings.Join(errs, "; "))
+}
+
+// Meta 查询key在其归属节点上的缓存情况，不传输值本身
+// 目标节点需要通过HTTPPool.SetDebug开启元数据调试接口
+func (c *Client) Meta(ctx context.Context, group, key string) (KeyMeta, error) {
+	var meta KeyMeta
+	peer := c.ring.Get(key)
+	if peer == "" {
+		return meta, fmt.Errorf("gocachex: client has no peers")
+	}
+
+	u := fmt.Sprintf("%v%v%v/%v", peer+defaultBasePath, metaPrefix, url.QueryEscape(group), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return meta, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return meta, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return meta, fmt.Errorf("server returned: %v", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&meta); err != nil {
+		return meta, fmt.Errorf("decoding response body: %v", err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,102 @@
+package gocachex
+
+import (
+	"context"
+	"fmt"
+	"goCacheX/consistenthash"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	NewGroup("client", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("value-of-" + key), nil }))
+
+	// 启动3个节点
+	servers := make(map[string]*httptest.Server)
+	var peers []string
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(NewHTTPPool("localhost:9999"))
+		defer server.Close()
+		servers[server.URL] = server
+		peers = append(peers, server.URL)
+	}
+
+	client, err := NewClient(peers, defaultReplicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := client.Get(context.Background(), "client", "Tom")
+	if err != nil || string(v) != "value-of-Tom" {
+		t.Fatalf("expect value-of-Tom, got %q (err=%v)", v, err)
+	}
+
+	// 关闭归属节点后转移到下一个节点
+	servers[client.ring.Get("Tom")].Close()
+	v, err = client.Get(context.Background(), "client", "Tom")
+	if err != nil || string(v) != "value-of-Tom" {
+		t.Fatalf("expect failover to serve value-of-Tom, got %q (err=%v)", v, err)
+	}
+
+	if _, err := client.Get(context.Background(), "no-such-group", "Tom"); err == nil {
+		t.Fatal("expect error for unknown group")
+	}
+}
+
+func TestClientMeta(t *testing.T) {
+	gee := NewGroup("client-meta", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("value-of-" + key), nil }))
+	gee.Get("Tom")
+
+	pool := NewHTTPPool("localhost:9999")
+	server := httptest.NewServer(pool)
+	defer server.Close()
+	client, err := NewClient([]string{server.URL}, defaultReplicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 默认关闭调试接口
+	if _, err := client.Meta(context.Background(), "client-meta", "Tom"); err == nil {
+		t.Fatal("meta endpoint should be disabled by default")
+	}
+
+	pool.SetDebug(true)
+	meta, err := client.Meta(context.Background(), "client-meta", "Tom")
+	if err != nil || !meta.Cached || meta.Size != len("value-of-Tom") {
+		t.Fatalf("unexpected meta for cached key: %+v (err=%v)", meta, err)
+	}
+	meta, err = client.Meta(context.Background(), "client-meta", "Jack")
+	if err != nil || meta.Cached || meta.Size != 0 {
+		t.Fatalf("unexpected meta for absent key: %+v (err=%v)", meta, err)
+	}
+}
+
+func TestClientFromPoolRing(t *testing.T) {
+	if _, err := NewClient([]string{"http://a"}, 0); err == nil {
+		t.Fatal("expect error for zero replicas")
+	}
+
+	pool := NewHTTPPool("http://a")
+	if _, err := pool.ExportRing(); err == nil {
+		t.Fatal("expect error before Set")
+	}
+	pool.SetSalt([]byte("cluster-secret"))
+	pool.SetVNodeFormat(consistenthash.LegacyVNodeFormat)
+	pool.Set("http://a", "http://b", "http://c")
+	data, err := pool.ExportRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientFromRing(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 客户端与节点池使用相同的盐值和虚拟节点格式，对每个key的归属一致
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if got, want := client.ring.Get(key), pool.peers.Get(key); got != want {
+			t.Fatalf("key %s: client routes to %s, pool to %s", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gocachex
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+// blob 是去重后共享的一份值数据
+type blob struct {
+	b    []byte // 共享的底层数据
+	refs int    // 引用该数据的缓存项数量
+}
+
+// WithValueDedup 开启值去重：内容相同的值只存储一份，多个缓存项共享同一底层数据
+// 适合大量key对应相同值的场景（例如默认头像）。被共享的数据在最后一个引用它的缓存项被淘汰后释放。
+//
+// 代价：每次写入缓存都要对整个值计算一次FNV-64a哈希，哈希相同时再逐字节比较，
+// 写入开销与值的大小成正比。内存上限仍按每个缓存项各自的值大小计算，
+// 因此去重节省的是实际内存，不会让缓存容纳更多条目。与WithBufferPool同时使用时不生效
+func WithValueDedup() GroupOption {
+	return func(g *Group) {
+		g.mainCache.dedup = make(map[uint64]*blob)
+	}
+}
+
+// intern 返回与value内容相同的共享值并增加引用计数，调用方需持有c.mu
+func (c *cache) intern(value ByteView) ByteView {
+	if len(value.b) == 0 {
+		return value
+	}
+	sum := contentHash(value.b)
+	if bl, ok := c.dedup[sum]; ok {
+		if bytes.Equal(bl.b, value.b) {
+			bl.refs++
+			return ByteView{b: bl.b}
+		}
+		return value // 哈希冲突，不去重
+	}
+	c.dedup[sum] = &blob{b: value.b, refs: 1}
+	return value
+}
+
+// release 减少value对应共享数据的引用计数，降为0时释放，调用方需持有c.mu
+func (c *cache) release(value ByteView) {
+	if len(value.b) == 0 {
+		return
+	}
+	sum := contentHash(value.b)
+	bl, ok := c.dedup[sum]
+	if !ok || &bl.b[0] != &value.b[0] { // 因哈希冲突未去重的值
+		return
+	}
+	if bl.refs--; bl.refs == 0 {
+		delete(c.dedup, sum)
+	}
+}
+
+// contentHash 计算值内容的FNV-64a哈希
+func contentHash(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
@@ -0,0 +1,30 @@
+package gocachex
+
+import (
+	"expvar"
+	"sync"
+)
+
+var expvarOnce sync.Once
+
+// PublishExpvar 通过标准库expvar发布所有Group的统计信息
+// 发布在名为 "gocachex" 的变量下，按Group名称组织，可以通过 /debug/vars 查看。
+// 统计信息只在读取时计算，未调用本函数时没有任何开销；重复调用是安全的
+func PublishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish("gocachex", expvar.Func(func() any {
+			mu.RLock()
+			snapshot := make(map[string]*Group, len(groups))
+			for name, g := range groups {
+				snapshot[name] = g
+			}
+			mu.RUnlock()
+
+			vars := make(map[string]Stats, len(snapshot))
+			for name, g := range snapshot {
+				vars[name] = g.Stats()
+			}
+			return vars
+		}))
+	})
+}
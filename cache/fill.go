@@ -0,0 +1,81 @@
+package gocachex
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// ErrFillDone 表示对已经提交或放弃的Fill继续操作
+var ErrFillDone = errors.New("gocachex: fill already committed or aborted")
+
+// errFillAborted 是等待中的加载在分阶段写入被放弃时得到的错误，加载方收到后重新加载
+var errFillAborted = errors.New("gocachex: fill aborted")
+
+// Fill 是一次分阶段的缓存写入
+// 通过Write分多次写入数据，在Commit之前key对缓存完全不可见，并发的Get不会读到只写了一半的组合值。
+// Fill不是并发安全的，应由单个协程完成写入
+type Fill struct {
+	g    *Group
+	key  string
+	buf  bytes.Buffer
+	done bool
+
+	release func(val any, err error) // 释放在写入期间合并到本次写入的加载，为nil表示没有登记
+}
+
+// BeginFill 开始一次分阶段写入
+// 开始时key没有进行中的加载，本次写入会登记为该key的加载：写入期间未命中的Get不调用getter，
+// 而是等待Commit并得到提交的值；Abort后它们照常加载。因此每个Fill都必须Commit或Abort（例如defer f.Abort()），
+// 否则这些Get会一直等待。开始时已有进行中的加载，或Group关闭了合并（WithoutSingleflight）时不登记，
+// 写入期间的Get正常未命中并加载，Commit会覆盖期间加载的值
+func (g *Group) BeginFill(key string) *Fill {
+	f := &Fill{g: g, key: g.normalize(key)}
+	if !g.noSingleflight && f.key != "" {
+		f.release, _ = g.loader.Begin(f.key)
+	}
+	return f
+}
+
+// Write 追加数据，实现io.Writer接口
+func (f *Fill) Write(p []byte) (int, error) {
+	if f.done {
+		return 0, ErrFillDone
+	}
+	return f.buf.Write(p)
+}
+
+// Commit 将写入的完整数据原子地放入缓存，此后key才对Get可见
+// ttl大于0时缓存项在ttl后过期，不大于0表示只受容量淘汰。迁移淘汰策略（见MigratePolicy）后
+// 新策略不支持过期时间，此时ttl大于0返回错误，Fill保持未完成，可以改用Commit(0)或Abort
+func (f *Fill) Commit(ttl time.Duration) error {
+	if f.done {
+		return ErrFillDone
+	}
+	if f.key == "" {
+		return errors.New("key is required")
+	}
+	if ttl > 0 && f.g.mainCache.migrated() {
+		return errPolicyTTL
+	}
+	f.done = true
+	value := ByteView{b: f.buf.Bytes()}
+	f.g.populateCacheTTL(f.key, value, ttl)
+	if f.release != nil {
+		f.release(value, nil)
+	}
+	return nil
+}
+
+// Abort 放弃本次写入，已写入的数据被丢弃
+// 对已经提交或放弃的Fill调用Abort没有作用
+func (f *Fill) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.buf = bytes.Buffer{}
+	if f.release != nil {
+		f.release(nil, errFillAborted)
+	}
+}
@@ -0,0 +1,113 @@
+package gocachex
+
+import (
+	"errors"
+	"goCacheX/lru"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFill(t *testing.T) {
+	var calls atomic.Int32
+	gee := NewGroup("fill", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			calls.Add(1)
+			return nil, errors.New("not found")
+		}))
+
+	fill := gee.BeginFill("composite")
+	fill.Write([]byte("part1,"))
+	if _, ok := gee.mainCache.get("composite"); ok {
+		t.Fatal("key should be invisible before Commit")
+	}
+	// 写入期间的Get合并到本次写入，等待提交的值而不调用getter
+	got := make(chan string)
+	go func() {
+		view, _ := gee.Get("composite")
+		got <- view.String()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	fill.Write([]byte("part2"))
+
+	if err := fill.Commit(0); err != nil {
+		t.Fatal(err)
+	}
+	if v := <-got; v != "part1,part2" || calls.Load() != 0 {
+		t.Fatalf("Get during the fill should wait for the commit, got %q with %d getter calls", v, calls.Load())
+	}
+	if view, err := gee.Get("composite"); err != nil || view.String() != "part1,part2" {
+		t.Fatalf("expect committed value, got %q (err=%v)", view.String(), err)
+	}
+	if err := fill.Commit(0); !errors.Is(err, ErrFillDone) {
+		t.Fatalf("expect ErrFillDone on second Commit, got %v", err)
+	}
+
+	aborted := gee.BeginFill("aborted")
+	aborted.Write([]byte("partial"))
+	errc := make(chan error)
+	go func() {
+		_, err := gee.Get("aborted")
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	aborted.Abort()
+	// 放弃后等待的Get照常加载，得到getter的结果
+	if err := <-errc; err == nil || errors.Is(err, errFillAborted) || calls.Load() != 1 {
+		t.Fatalf("Get should load after the fill is aborted, got %v with %d getter calls", err, calls.Load())
+	}
+	if _, err := aborted.Write([]byte("more")); !errors.Is(err, ErrFillDone) {
+		t.Fatalf("expect ErrFillDone after Abort, got %v", err)
+	}
+	if _, ok := gee.mainCache.get("aborted"); ok {
+		t.Fatal("aborted fill should not be cached")
+	}
+}
+
+// TestFillWithoutSingleflight 测试不合并加载时写入期间的Get正常加载，Commit覆盖期间加载的值
+func TestFillWithoutSingleflight(t *testing.T) {
+	gee := NewGroup("fill-no-singleflight", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("loaded"), nil }), WithoutSingleflight())
+
+	fill := gee.BeginFill("k")
+	fill.Write([]byte("filled"))
+	if view, err := gee.Get("k"); err != nil || view.String() != "loaded" {
+		t.Fatalf("Get during the fill should load, got %q (err=%v)", view.String(), err)
+	}
+	if err := fill.Commit(0); err != nil {
+		t.Fatal(err)
+	}
+	if view, _ := gee.Get("k"); view.String() != "filled" {
+		t.Fatalf("Commit should overwrite the loaded value, got %q", view.String())
+	}
+}
+
+// TestFillTTL 测试提交时设置的过期时间
+func TestFillTTL(t *testing.T) {
+	gee := NewGroup("fill-ttl", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("loaded"), nil }))
+	defer gee.Close()
+
+	fill := gee.BeginFill("k")
+	fill.Write([]byte("filled"))
+	if err := fill.Commit(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if view, _ := gee.Get("k"); view.String() != "filled" {
+		t.Fatalf("expect committed value before expiry, got %q", view.String())
+	}
+	time.Sleep(40 * time.Millisecond)
+	if view, _ := gee.Get("k"); view.String() != "loaded" {
+		t.Fatalf("expired fill should reload, got %q", view.String())
+	}
+
+	// 迁移淘汰策略后不支持过期时间
+	if err := gee.MigratePolicy(lru.NewARCPolicy(8)); err != nil {
+		t.Fatal(err)
+	}
+	fill = gee.BeginFill("migrated")
+	defer fill.Abort()
+	if err := fill.Commit(time.Minute); err == nil {
+		t.Fatal("expect an error for ttl after migrating the policy")
+	}
+}
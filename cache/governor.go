@@ -0,0 +1,99 @@
+// governor.go 实现了进程级的内存上限控制
+// 每个Group的cacheBytes只限制自身，多个Group叠加后仍可能耗尽进程内存；
+// 设置全局上限后，所有Group的缓存总量超过上限时，按淘汰策略选出一个Group淘汰其最旧的条目，
+// 直到总量回到上限以内
+package gocachex
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// VictimPolicy 在全局内存超限时从候选Group中选出需要淘汰数据的Group
+// 候选列表中的Group都至少缓存了一个条目
+type VictimPolicy func(candidates []*Group) *Group
+
+// LowestHitRatio 选择命中率最低的Group，是默认的淘汰策略
+func LowestHitRatio(candidates []*Group) *Group {
+	var victim *Group
+	var lowest float64
+	for _, g := range candidates {
+		if ratio := g.Stats().HitRatio(); victim == nil || ratio < lowest {
+			victim, lowest = g, ratio
+		}
+	}
+	return victim
+}
+
+var (
+	governorMu     sync.Mutex                    // 保证同一时间只有一次全局淘汰
+	globalMaxBytes atomic.Int64                  // 所有Group缓存总量的上限，0表示不限制
+	victimPolicy   VictimPolicy = LowestHitRatio // 超限时的淘汰策略，由governorMu保护
+)
+
+// SetGlobalMaxBytes 设置所有Group缓存总量的上限（字节），0表示不限制
+func SetGlobalMaxBytes(maxBytes int64) {
+	globalMaxBytes.Store(maxBytes)
+	enforceGlobalMaxBytes()
+}
+
+// SetVictimPolicy 设置全局内存超限时的淘汰策略，nil表示恢复默认的LowestHitRatio
+func SetVictimPolicy(policy VictimPolicy) {
+	governorMu.Lock()
+	defer governorMu.Unlock()
+	if policy == nil {
+		policy = LowestHitRatio
+	}
+	victimPolicy = policy
+}
+
+// governorBatch 是全局淘汰每次选中Group后最多连续淘汰的缓存项数量
+const governorBatch = 64
+
+// enforceGlobalMaxBytes 在缓存总量超过全局上限时淘汰数据
+// 未设置全局上限时不加锁直接返回，不会让各Group的写入互相等待。
+// 各Group的占用只在开始时统计一次，之后减去每批淘汰释放的字节数；每次选出的Group在一次加锁中
+// 淘汰一批缓存项，直到补足超出的部分或达到governorBatch。一批淘汰没有释放内存的Group
+// （例如迁移后的淘汰策略无法再淘汰）不再作为候选；淘汰策略返回不在候选列表中的Group时停止，避免持有锁无限循环
+func enforceGlobalMaxBytes() {
+	if globalMaxBytes.Load() <= 0 {
+		return
+	}
+	governorMu.Lock()
+	defer governorMu.Unlock()
+
+	mu.RLock()
+	all := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		all = append(all, g)
+	}
+	mu.RUnlock()
+
+	var total int64
+	sizes := make(map[*Group]int64, len(all))
+	candidates := all[:0:0]
+	for _, g := range all {
+		if n := g.mainCache.bytes(); n > 0 {
+			total += n
+			sizes[g] = n
+			candidates = append(candidates, g)
+		}
+	}
+	for len(candidates) > 0 {
+		excess := total - globalMaxBytes.Load()
+		if excess <= 0 {
+			return
+		}
+		victim := victimPolicy(candidates)
+		if victim == nil || !slices.Contains(candidates, victim) {
+			return
+		}
+		freed := victim.mainCache.evictBytes(excess, governorBatch)
+		total -= freed
+		sizes[victim] -= freed
+		if freed == 0 || sizes[victim] <= 0 {
+			candidates = slices.DeleteFunc(candidates, func(g *Group) bool { return g == victim })
+		}
+	}
+}
@@ -1,15 +1,22 @@
 package gocachex
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"goCacheX/consistenthash"
 	pb "goCacheX/gocacheXpb"
 	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 )
@@ -18,6 +25,12 @@ import (
 const (
 	defaultBasePath = "/_gocacheX/" // 默认的HTTP请求路径前缀
 	defaultReplicas = 50            // 一致性哈希的默认虚拟节点数
+	metaPrefix      = "_meta/"      // 元数据调试接口的路径前缀，位于basePath之后
+	hotKeysPrefix   = "_hotkeys/"   // 热点键列表接口的路径前缀，位于basePath之后
+
+	// forwardedHeader 标记请求来自其他节点，收到这样的请求时只在本地加载而不再转发，
+	// 避免各节点对归属的判断不一致（例如节点下线中）时请求在节点之间来回转发
+	forwardedHeader = "X-GoCacheX-Forwarded"
 )
 
 // HTTPPool 实现了 PeerPicker 接口，用于管理HTTP节点池
@@ -27,6 +40,22 @@ type HTTPPool struct {
 	mu          sync.Mutex             // 互斥锁，保护并发访问
 	peers       *consistenthash.Map    // 一致性哈希映射，用于节点选择
 	httpGetters map[string]*httpGetter // 节点到httpGetter的映射，用于向其他节点发送HTTP请求获取缓存数据
+	salt        []byte                 // 一致性哈希使用的盐值，为空时不加盐
+	hashName    string                 // 一致性哈希使用的哈希函数注册名
+	vnodeFmt    string                 // 虚拟节点名称的格式，为空时使用consistenthash.DefaultVNodeFormat
+	debug       bool                   // 是否开放元数据调试接口
+	hotKeys     bool                   // 是否开放热点键列表接口，供新节点预热
+	draining    bool                   // 本节点正在下线，归属本节点的key转交给哈希环上的下一个节点
+
+	debounce     time.Duration // 哈希环重建的合并窗口，0表示每次Set立即重建
+	pendingPeers []string      // 合并窗口内最新的节点列表，等待重建
+	rebuildTimer *time.Timer   // 合并窗口结束时执行重建，为nil表示没有待重建的变更
+
+	ringWatchers []RingChangeFunc // 哈希环重建后调用，见OnRingChange
+	hashChanged  bool             // 上次重建后修改过哈希函数、盐值或虚拟节点格式，新旧哈希环不可比较
+
+	emptyRingPicks atomic.Int64 // 在空哈希环上选择节点的次数，通常说明忘记调用Set
+	rebuilds       atomic.Int64 // 哈希环重建的次数
 }
 
 // NewHTTPPool 初始化一个HTTP节点池
@@ -34,6 +63,7 @@ func NewHTTPPool(self string) *HTTPPool {
 	return &HTTPPool{
 		self:     self,
 		basePath: defaultBasePath,
+		hashName: consistenthash.HashCRC32,
 	}
 }
 
@@ -50,6 +80,18 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	p.Log("%s %s", r.Method, r.URL.Path)
 
+	p.mu.Lock()
+	debug, hotKeys := p.debug, p.hotKeys
+	p.mu.Unlock()
+	if debug && strings.HasPrefix(r.URL.Path[len(p.basePath):], metaPrefix) {
+		p.serveMeta(w, r.URL.Path[len(p.basePath)+len(metaPrefix):])
+		return
+	}
+	if hotKeys && strings.HasPrefix(r.URL.Path[len(p.basePath):], hotKeysPrefix) {
+		p.serveHotKeys(w, r)
+		return
+	}
+
 	// 解析请求路径：/<basepath>/<groupname>/<key>
 	parts := strings.SplitN(r.URL.Path[len(p.basePath):], "/", 2)
 	if len(parts) != 2 {
@@ -67,13 +109,29 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 从缓存组获取数据
-	view, err := group.Get(key)
+	// 从缓存组获取数据，其他节点转发来的请求只在本地加载
+	var view ByteView
+	var err error
+	if r.Header.Get(forwardedHeader) != "" {
+		view, err = group.getForwarded(r.Context(), key)
+	} else {
+		view, err = group.Get(key)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writePeerError(w, err)
 		return
 	}
 
+	// 调用方持有的版本与当前值一致时，只返回304而不重复发送数据
+	// 计算版本需要对整个值做哈希，只在条件请求时计算
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if version := view.Version(); inm == version {
+			w.Header().Set("ETag", version)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	// 将数据序列化为protobuf格式
 	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
 	if err != nil {
@@ -86,14 +144,178 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// peerErrNotFound 是表示对端确认数据不存在的错误类别
+const peerErrNotFound = "not_found"
+
+// PeerError 是远程节点以JSON返回的加载错误，保留了对端的原始错误信息
+// 对端的错误包装了ErrNotFound时，errors.Is(err, ErrNotFound)同样成立
+type PeerError struct {
+	Status  int    `json:"-"`     // HTTP状态码
+	Code    string `json:"code"`  // 错误类别，not_found表示对端确认数据不存在，其他错误为internal
+	Message string `json:"error"` // 对端的原始错误信息
+}
+
+func (e *PeerError) Error() string {
+	return fmt.Sprintf("peer returned %d: %s", e.Status, e.Message)
+}
+
+// Is 让errors.Is(err, ErrNotFound)识别对端的未找到错误
+func (e *PeerError) Is(target error) bool {
+	return target == ErrNotFound && e.Code == peerErrNotFound
+}
+
+// writePeerError 将加载错误以JSON写入响应，未找到返回404，其他错误返回500
+func writePeerError(w http.ResponseWriter, err error) {
+	perr := PeerError{Status: http.StatusInternalServerError, Code: "internal", Message: err.Error()}
+	if errors.Is(err, ErrNotFound) {
+		perr.Status, perr.Code = http.StatusNotFound, peerErrNotFound
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(perr.Status)
+	json.NewEncoder(w).Encode(perr)
+}
+
+// decodePeerError 解析对端返回的JSON错误，响应不是结构化错误时返回nil
+// 旧版本节点和代理返回的纯文本错误页都会得到nil，由调用方按状态码处理
+func decodePeerError(res *http.Response) *PeerError {
+	if mt, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		return nil
+	}
+	var perr PeerError
+	if err := json.NewDecoder(res.Body).Decode(&perr); err != nil || perr.Message == "" {
+		return nil
+	}
+	perr.Status = res.StatusCode
+	return &perr
+}
+
+// SetDebug 开启或关闭元数据调试接口 GET <basePath>_meta/<group>/<key>
+// 该接口返回key在本节点的缓存情况而不返回值本身，默认关闭
+func (p *HTTPPool) SetDebug(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.debug = enabled
+}
+
+// KeyMeta 描述key在某个节点上的缓存情况
+type KeyMeta struct {
+	Group  string `json:"group"`  // 缓存组名称
+	Key    string `json:"key"`    // 缓存键
+	Cached bool   `json:"cached"` // 是否缓存在该节点
+	Size   int    `json:"size"`   // 值的大小（字节），未缓存时为0
+}
+
+// serveMeta 处理元数据调试请求，path格式为 <groupname>/<key>
+func (p *HTTPPool) serveMeta(w http.ResponseWriter, path string) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	group := GetGroup(parts[0])
+	if group == nil {
+		http.Error(w, "no such group: "+parts[0], http.StatusNotFound)
+		return
+	}
+
+	meta := KeyMeta{Group: parts[0], Key: parts[1]}
+	// 只查看不刷新访问顺序，调试查询不影响淘汰
+	if v, ok := group.mainCache.peek(parts[1]); ok {
+		meta.Cached = true
+		meta.Size = v.Len()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(meta)
+}
+
+// SetSalt 设置一致性哈希的盐值，在下一次调用Set时生效
+// 集群内所有节点必须设置相同的盐值；Go的map本身已使用进程级随机种子，无需额外加盐
+func (p *HTTPPool) SetSalt(salt []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.salt = append([]byte(nil), salt...)
+	p.hashChanged = true
+}
+
+// SetHash 选择一致性哈希使用的已注册哈希函数，在下一次调用Set时生效
+// 默认使用crc32；例如传入consistenthash.HashXXHash可获得更均匀的分布（环上位置仍为32位，冲突概率不变）。
+// 注意：更换哈希函数会改变所有key的归属，与旧配置不兼容，集群内所有节点必须同时切换
+func (p *HTTPPool) SetHash(name string) error {
+	if _, err := consistenthash.NewMapByName(1, name); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hashName = name
+	p.hashChanged = true
+	return nil
+}
+
+// SetVNodeFormat 设置一致性哈希虚拟节点名称的格式，在下一次调用Set时生效，空字符串表示默认格式
+// 默认的consistenthash.DefaultVNodeFormat与早期版本使用的consistenthash.LegacyVNodeFormat
+// 生成的哈希环不同，升级后大部分key的归属会改变，新旧版本的节点混合运行时会对归属产生分歧。
+// 滚动升级时应在所有节点上先设置LegacyVNodeFormat，需要切换到新格式时再让所有节点同时切换，
+// 切换相当于一次全量的重新分片，各节点的本地缓存会暂时失效
+func (p *HTTPPool) SetVNodeFormat(format string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.vnodeFmt = format
+	p.hashChanged = true
+}
+
+// SetRebuildDebounce 设置哈希环重建的合并窗口
+// 开启后，Set不再立即重建哈希环：窗口内的多次Set只在窗口结束时按最后一次的节点列表重建一次，
+// 避免服务发现频繁抖动（例如部署期间Pod反复上下线）时路由不断变化。
+// 代价是节点变更最多延迟window才生效；window不大于0时恢复为每次Set立即重建
+func (p *HTTPPool) SetRebuildDebounce(window time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.debounce = window
+}
+
 // Set 设置节点池中的节点
 func (p *HTTPPool) Set(peers ...string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.debounce <= 0 {
+		p.rebuildLocked(peers)
+		return
+	}
+	// 在合并窗口内只记录最新的节点列表，窗口结束时统一重建
+	p.pendingPeers = append([]string(nil), peers...)
+	if p.rebuildTimer == nil {
+		p.rebuildTimer = time.AfterFunc(p.debounce, func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.rebuildTimer = nil
+			p.rebuildLocked(p.pendingPeers)
+			p.pendingPeers = nil
+		})
+	}
+}
+
+// rebuildLocked 按节点列表重建哈希环和httpGetter，调用方需持有p.mu
+func (p *HTTPPool) rebuildLocked(peers []string) {
+	p.rebuilds.Add(1)
+
 	// 初始化一致性哈希映射
-	p.peers = consistenthash.NewMap(defaultReplicas, nil)
+	old := p.peers
+	p.peers, _ = consistenthash.NewMapByName(defaultReplicas, p.hashName) // 名称已在SetHash中校验
+	p.peers.SetSalt(p.salt)
+	p.peers.SetVNodeFormat(p.vnodeFmt)
 	p.peers.Add(peers...)
+	if p.hashChanged {
+		old = nil // 与空哈希环比较，所有范围都视为发生了变化
+		p.hashChanged = false
+	}
+	if len(p.ringWatchers) > 0 {
+		delta := p.peers.Diff(old)
+		for _, fn := range p.ringWatchers {
+			fn(delta, p.self)
+		}
+	}
 
 	// 为每个节点创建httpGetter
 	p.httpGetters = make(map[string]*httpGetter, len(peers))
@@ -103,29 +325,138 @@ func (p *HTTPPool) Set(peers ...string) {
 	}
 }
 
+// OnRingChange 注册一个在哈希环重建后调用的函数，参数为新旧哈希环之间的变化和当前节点
+// fn在持有节点池的锁时被调用，必须尽快返回，且不能再调用节点池的方法。
+// 修改哈希函数、盐值或虚拟节点格式后新旧哈希环不可比较，此后第一次重建时整个哈希环都视为发生了变化
+func (p *HTTPPool) OnRingChange(fn RingChangeFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ringWatchers = append(p.ringWatchers, fn)
+}
+
+// RingRebuilds 返回哈希环重建的次数
+func (p *HTTPPool) RingRebuilds() int64 {
+	return p.rebuilds.Load()
+}
+
+// ExportRing 导出当前的哈希环，供NewClientFromRing创建直接路由的客户端
+// 导出内容包含哈希函数、盐值和虚拟节点格式，客户端因此与本节点对key的归属保持一致。
+// 尚未调用Set（或合并窗口内的首次重建尚未完成）时返回错误
+func (p *HTTPPool) ExportRing() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, errors.New("gocachex: peers not set")
+	}
+	return p.peers.Export(), nil
+}
+
 // PickPeer 根据key选择一个节点
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	if p.peers.IsEmpty() {
+		p.noteEmptyRing()
+		return nil, false
+	}
+
 	// 通过一致性哈希选择节点，并防止选择自身
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+	peer := p.peers.Get(key)
+	if peer == p.self && p.draining {
+		// 下线中的节点不再本地加载，转交给哈希环上的下一个节点
+		peer = ""
+		if next := p.peers.GetN(key, 2); len(next) == 2 {
+			peer = next[1]
+		}
+	}
+	if peer != "" && peer != p.self {
 		p.Log("Pick peer %s", peer)
 		return p.httpGetters[peer], true
 	}
 	return nil, false
 }
 
-// 确保HTTPPool实现了PeerPicker接口
+// SetDraining 标记本节点是否正在下线
+// 下线期间归属本节点的key不再在本地加载，而是转交给哈希环上的下一个节点，
+// 其他节点的归属不变；健康时（默认）归属本节点的key照常在本地加载。
+// 其他节点转发来的请求（它们的哈希环仍把key分给本节点）照常在本地处理，不会再转发
+func (p *HTTPPool) SetDraining(draining bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draining = draining
+}
+
+// PickPeers 按哈希环顺序返回key的前n个候选节点，遇到自身时截断；本节点下线中时跳过自身
+func (p *HTTPPool) PickPeers(key string, n int) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.peers.IsEmpty() {
+		p.noteEmptyRing()
+		return nil
+	}
+	var getters []PeerGetter
+	for _, peer := range p.peers.GetN(key, n) {
+		if peer == p.self && p.draining {
+			continue
+		}
+		if peer == p.self {
+			break
+		}
+		getters = append(getters, p.httpGetters[peer])
+	}
+	return getters
+}
+
+// noteEmptyRing 记录一次在空哈希环上的节点选择，首次发生时输出日志
+// 此时所有key都会在本地加载，通常是配置错误（忘记调用Set或节点列表为空）
+func (p *HTTPPool) noteEmptyRing() {
+	if p.emptyRingPicks.Add(1) == 1 {
+		p.Log("PickPeer called on an empty ring, all keys will be loaded locally; was Set called?")
+	}
+}
+
+// EmptyRingPicks 返回在空哈希环上选择节点的次数
+func (p *HTTPPool) EmptyRingPicks() int64 {
+	return p.emptyRingPicks.Load()
+}
+
+// 确保HTTPPool实现了PeerPicker和PeersPicker接口
 var _ PeerPicker = (*HTTPPool)(nil)
+var _ PeersPicker = (*HTTPPool)(nil)
 
 // httpGetter 实现了PeerGetter接口，用于从其他节点获取数据
 type httpGetter struct {
-	baseURL string // 基础URL，用于构建完整的请求URL
+	baseURL string       // 基础URL，用于构建完整的请求URL
+	client  *http.Client // 发送请求使用的客户端，为nil时使用http.DefaultClient
+}
+
+// String 返回节点的基础URL，用于日志中区分节点
+func (h *httpGetter) String() string {
+	return h.baseURL
 }
 
 // Get 通过HTTP请求获取指定group的key数据
 func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+	_, err := h.GetIfModified(in, out, "")
+	return err
+}
+
+// GetContext 与Get相同，请求受ctx控制，ctx结束时请求被取消
+func (h *httpGetter) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	_, err := h.get(ctx, in, out, "")
+	return err
+}
+
+// GetIfModified 通过HTTP请求获取数据，version不为空时携带If-None-Match请求头
+// 远端返回304时说明数据未变化，返回modified=false且不填充out
+func (h *httpGetter) GetIfModified(in *pb.Request, out *pb.Response, version string) (bool, error) {
+	return h.get(context.Background(), in, out, version)
+}
+
+// get 发送HTTP请求获取数据，请求受ctx控制
+func (h *httpGetter) get(ctx context.Context, in *pb.Request, out *pb.Response, version string) (bool, error) {
 	// 构建请求URL
 	u := fmt.Sprintf(
 		"%v%v/%v",
@@ -133,32 +464,66 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 		url.QueryEscape(in.GetGroup()), // 对group名称进行URL编码
 		url.QueryEscape(in.GetKey()),   // 对key进行URL编码
 	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	if version != "" {
+		req.Header.Set("If-None-Match", version)
+	}
+	req.Header.Set(forwardedHeader, "1")
 
 	// 发送GET请求
-	res, err := http.Get(u)
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
 	if err != nil {
-		return err
+		// 将HTTP客户端超时统一映射为context.DeadlineExceeded，便于调用方区分超时与其他错误
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() && !errors.Is(err, context.DeadlineExceeded) {
+			return false, fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+		}
+		return false, err
 	}
 	defer res.Body.Close()
 
-	// 检查响应状态码
+	// 数据未变化
+	if res.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+
+	// 检查响应状态码，对端返回了结构化错误时保留原始错误信息
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned: %v", res.Status)
+		if perr := decodePeerError(res); perr != nil {
+			return false, perr
+		}
+		return false, fmt.Errorf("server returned: %v", res.Status)
+	}
+
+	// 检查响应类型，误配置的代理可能返回HTML错误页，此时给出明确的错误而不是解码失败
+	if ct := res.Header.Get("Content-Type"); ct != "" {
+		if mt, _, err := mime.ParseMediaType(ct); err != nil || mt != "application/octet-stream" {
+			return false, fmt.Errorf("peer returned unexpected content type %q, want application/octet-stream", ct)
+		}
 	}
 
 	// 读取响应体
 	bytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("reading response body: %v", err)
+		return false, fmt.Errorf("reading response body: %v", err)
 	}
 
 	// 解析protobuf响应
 	if err = proto.Unmarshal(bytes, out); err != nil {
-		return fmt.Errorf("decoding response body: %v", err)
+		return false, fmt.Errorf("decoding response body: %v", err)
 	}
 
-	return nil
+	return true, nil
 }
 
-// 确保httpGetter实现了PeerGetter接口
+// 确保httpGetter实现了PeerGetter、ContextPeerGetter和ConditionalPeerGetter接口
 var _ PeerGetter = (*httpGetter)(nil)
+var _ ContextPeerGetter = (*httpGetter)(nil)
+var _ ConditionalPeerGetter = (*httpGetter)(nil)
@@ -1,14 +1,18 @@
 package gocachex_test
 
 import (
+	"errors"
 	"fmt"
 	gocachex "goCacheX/cache"
+	"goCacheX/consistenthash"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -86,3 +90,439 @@ func TestHTTPPool(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPPoolNotModified(t *testing.T) {
+	group := gocachex.NewGroup("not-modified", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(strings.Repeat("v", 1024)), nil
+		}))
+	server := httptest.NewServer(gocachex.NewHTTPPool("localhost:9999"))
+	defer server.Close()
+	url := server.URL + "/_gocacheX/not-modified/big"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("ETag") != "" {
+		t.Fatal("非条件请求不应计算ETag")
+	}
+	// 调用方根据自己持有的副本计算版本
+	view, _ := group.Get("big")
+	version := view.Version()
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", version)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusNotModified || len(body) != 0 {
+		t.Fatalf("期望304且无响应体, 得到 %d 和 %d 字节", resp.StatusCode, len(body))
+	}
+}
+
+func TestServeByteView(t *testing.T) {
+	gee := gocachex.NewGroup("serve-byteview", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) { return []byte("0123456789"), nil }))
+	view, err := gee.Get("digits")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		wantCode     int
+		wantBody     string
+		contentRange string
+	}{
+		{"完整内容", "", http.StatusOK, "0123456789", ""},
+		{"单个范围", "bytes=2-5", http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"后缀范围", "bytes=-3", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"无法满足的范围", "bytes=20-30", http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api?key=digits", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+			gocachex.ServeByteView(rec, req, view)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("状态码不匹配: 期望 %d, 得到 %d", tt.wantCode, rec.Code)
+			}
+			if rec.Header().Get("Accept-Ranges") != "bytes" {
+				t.Errorf("缺少 Accept-Ranges: bytes")
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range不匹配: 期望 %q, 得到 %q", tt.contentRange, got)
+			}
+			if tt.wantCode != http.StatusRequestedRangeNotSatisfiable && rec.Body.String() != tt.wantBody {
+				t.Errorf("响应内容不匹配: 期望 %q, 得到 %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPPoolEmptyRing(t *testing.T) {
+	peers := gocachex.NewHTTPPool("http://localhost:8001")
+
+	// 未调用Set时不应panic，并且可以观测到
+	if _, ok := peers.PickPeer("Tom"); ok {
+		t.Fatal("空哈希环不应选出节点")
+	}
+	peers.Set()
+	peers.PickPeers("Tom", 2)
+	if n := peers.EmptyRingPicks(); n != 2 {
+		t.Fatalf("期望记录2次空哈希环选择, 得到 %d", n)
+	}
+
+	peers.Set("http://localhost:8001", "http://localhost:8002")
+	peers.PickPeer("Tom")
+	if n := peers.EmptyRingPicks(); n != 2 {
+		t.Fatalf("非空哈希环不应计数, 得到 %d", n)
+	}
+}
+
+func TestHTTPPoolNonProtobufPeer(t *testing.T) {
+	// 误配置的代理对所有请求返回HTML错误页
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><body>502 Bad Gateway</body></html>")
+	}))
+	defer proxy.Close()
+
+	group := gocachex.NewGroup("html-peer", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) { return []byte("local-" + key), nil }))
+	peers := gocachex.NewHTTPPool("http://localhost:9999")
+	peers.Set(proxy.URL)
+	group.RegisterPeers(peers)
+
+	// 远程解码失败视为节点失败，回退到本地加载
+	view, err := group.Get("Tom")
+	if err != nil || view.String() != "local-Tom" {
+		t.Fatalf("期望回退到本地加载, 得到 %q (err=%v)", view.String(), err)
+	}
+}
+
+func TestAPIHandler(t *testing.T) {
+	group := gocachex.NewGroup("api-handler", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			if key == "broken" {
+				return nil, fmt.Errorf("backend unavailable")
+			}
+			return nil, fmt.Errorf("%s: %w", key, gocachex.ErrNotFound)
+		}))
+	handler := gocachex.APIHandler(group, time.Minute)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api?key=Tom", nil))
+	view, _ := group.Get("Tom")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || rec.Body.String() != "630" {
+		t.Fatalf("期望 200 和 630, 得到 %d %q", rec.Code, rec.Body.String())
+	}
+	if etag == "" || etag != view.Version() {
+		t.Fatalf("ETag应为值的版本 %q, 得到 %q", view.Version(), etag)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "max-age=60" {
+		t.Fatalf("期望 Cache-Control: max-age=60, 得到 %q", cc)
+	}
+
+	// 携带当前ETag的条件请求返回304且没有响应体
+	req := httptest.NewRequest(http.MethodGet, "/api?key=Tom", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("期望 304 且无响应体, 得到 %d %q", rec.Code, rec.Body.String())
+	}
+
+	// 不同的值有不同的ETag
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api?key=Jack", nil))
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("不同的值不应有相同的ETag")
+	}
+
+	// 缺少key返回400，数据不存在返回404，其他错误返回500
+	for _, tc := range []struct {
+		url  string
+		code int
+	}{
+		{"/api", http.StatusBadRequest},
+		{"/api?key=", http.StatusBadRequest},
+		{"/api?key=missing", http.StatusNotFound},
+		{"/api?key=broken", http.StatusInternalServerError},
+	} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != tc.code {
+			t.Fatalf("%s: 期望 %d, 得到 %d", tc.url, tc.code, rec.Code)
+		}
+	}
+}
+
+func TestHTTPPoolRebuildDebounce(t *testing.T) {
+	peers := gocachex.NewHTTPPool("http://localhost:8001")
+	peers.SetRebuildDebounce(50 * time.Millisecond)
+
+	// 部署期间节点列表快速抖动，最后只剩下本节点
+	for i := 0; i < 100; i++ {
+		peers.Set("http://localhost:8001", fmt.Sprintf("http://localhost:%d", 9000+i))
+	}
+	peers.Set("http://localhost:8001")
+	if n := peers.RingRebuilds(); n != 0 {
+		t.Fatalf("合并窗口内不应重建哈希环, 得到 %d 次", n)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if n := peers.RingRebuilds(); n != 1 {
+		t.Fatalf("期望只重建1次, 得到 %d 次", n)
+	}
+	// 重建使用最后一次的节点列表，所有key都归属本节点
+	for i := 0; i < 100; i++ {
+		if _, ok := peers.PickPeer(fmt.Sprintf("key%d", i)); ok {
+			t.Fatal("重建后的哈希环应只包含本节点")
+		}
+	}
+}
+
+func TestHTTPPoolDraining(t *testing.T) {
+	self := "http://localhost:8001"
+	peers := gocachex.NewHTTPPool(self)
+	peers.Set(self, "http://localhost:8002")
+
+	var owned []string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := peers.PickPeer(key); !ok {
+			owned = append(owned, key)
+		}
+	}
+	if len(owned) == 0 {
+		t.Fatal("本节点应至少拥有一个key")
+	}
+
+	// 下线中的节点把自己拥有的key转交给其他节点
+	peers.SetDraining(true)
+	for _, key := range owned {
+		if _, ok := peers.PickPeer(key); !ok {
+			t.Fatalf("下线中的节点不应本地加载 %s", key)
+		}
+		if got := peers.PickPeers(key, 2); len(got) != 1 {
+			t.Fatalf("期望跳过自身后剩1个候选节点, 得到 %d", len(got))
+		}
+	}
+
+	peers.SetDraining(false)
+	if _, ok := peers.PickPeer(owned[0]); ok {
+		t.Fatal("恢复后归属本节点的key应本地加载")
+	}
+}
+
+func TestHTTPPoolPeerError(t *testing.T) {
+	gocachex.NewGroup("peer-error", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, fmt.Errorf("%w: user %s", gocachex.ErrNotFound, key)
+			}
+			return nil, fmt.Errorf("database unavailable")
+		}))
+	// 两个分组在同一进程中，归属节点把客户端分组的请求转给peer-error，避免请求绕回客户端分组
+	pool := gocachex.NewHTTPPool("localhost:9999")
+	owner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.Replace(r.URL.Path, "peer-error-client", "peer-error", 1)
+		pool.ServeHTTP(w, r)
+	}))
+	defer owner.Close()
+
+	// 对端以JSON返回错误和原始信息
+	resp, err := http.Get(owner.URL + "/_gocacheX/peer-error/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(body), `"code":"not_found"`) {
+		t.Fatalf("expect a 404 JSON envelope, got %d %s", resp.StatusCode, body)
+	}
+
+	local := 0
+	client := gocachex.NewGroup("peer-error-client", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			local++
+			return []byte("local-" + key), nil
+		}))
+	peers := gocachex.NewHTTPPool("http://localhost:9999")
+	peers.Set(owner.URL)
+	client.RegisterPeers(peers)
+
+	// 归属节点确认不存在：原始错误传递给调用方，不再本地加载
+	_, err = client.Get("missing")
+	var perr *gocachex.PeerError
+	if !errors.Is(err, gocachex.ErrNotFound) || !errors.As(err, &perr) || !strings.Contains(perr.Message, "user missing") {
+		t.Fatalf("expect the owner's not-found error, got %v", err)
+	}
+	if local != 0 {
+		t.Fatalf("not-found on the owner should not fall back to the local getter, called %d times", local)
+	}
+
+	// 其他错误仍视为节点失败，回退到本地加载
+	if view, err := client.Get("Tom"); err != nil || view.String() != "local-Tom" {
+		t.Fatalf("expect local fallback for other peer errors, got %q (err=%v)", view.String(), err)
+	}
+}
+
+func TestRebalanceSweep(t *testing.T) {
+	self := "http://localhost:8011"
+	g := gocachex.NewGroup("rebalance-sweep", 0, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}), gocachex.WithRebalanceSweep(8, time.Millisecond))
+	defer g.Close()
+	peers := gocachex.NewHTTPPool(self)
+	g.RegisterPeers(peers)
+	peers.Set(self) // 所有key都归属本节点
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		if _, err := g.Get(keys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 新节点加入后，转移到新节点的key最终会从本地清理，仍归属本节点的key保留
+	peers.Set(self, "http://localhost:8012")
+	var moved, kept []string
+	for _, key := range keys {
+		if _, ok := peers.PickPeer(key); ok {
+			moved = append(moved, key)
+		} else {
+			kept = append(kept, key)
+		}
+	}
+	if len(moved) == 0 || len(kept) == 0 {
+		t.Fatalf("expect keys on both nodes, moved %d kept %d", len(moved), len(kept))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Stats().Len != len(kept) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len = %d after sweep, want %d", g.Stats().Len, len(kept))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := g.Stats().RebalanceEvictions; got != int64(len(moved)) {
+		t.Fatalf("RebalanceEvictions = %d, want %d", got, len(moved))
+	}
+	remaining := make(map[string]bool)
+	for _, key := range g.OldestKeys(len(keys)) {
+		remaining[key] = true
+	}
+	for _, key := range kept {
+		if !remaining[key] {
+			t.Fatalf("%s still belongs to self and should stay cached", key)
+		}
+	}
+}
+
+// TestHTTPPoolDrainingForwarding 测试两个节点中的一个下线时请求不会在节点之间来回转发
+func TestHTTPPoolDrainingForwarding(t *testing.T) {
+	var loadsA, loadsB atomic.Int64
+	gocachex.NewGroup("drain-a", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			loadsA.Add(1)
+			return []byte("a:" + key), nil
+		}))
+	groupB := gocachex.NewGroup("drain-b", 2<<10, gocachex.GetterFunc(
+		func(key string) ([]byte, error) {
+			loadsB.Add(1)
+			return []byte("b:" + key), nil
+		}))
+	groupA := gocachex.GetGroup("drain-a")
+
+	// 两个节点在同一进程中，各自把对端分组的请求改写为自己的分组
+	var poolA, poolB *gocachex.HTTPPool
+	serverA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.Replace(r.URL.Path, "drain-b", "drain-a", 1)
+		poolA.ServeHTTP(w, r)
+	}))
+	defer serverA.Close()
+	serverB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.Replace(r.URL.Path, "drain-a", "drain-b", 1)
+		poolB.ServeHTTP(w, r)
+	}))
+	defer serverB.Close()
+	poolA, poolB = gocachex.NewHTTPPool(serverA.URL), gocachex.NewHTTPPool(serverB.URL)
+	poolA.Set(serverA.URL, serverB.URL)
+	poolB.Set(serverA.URL, serverB.URL)
+	groupA.RegisterPeers(poolA)
+	groupB.RegisterPeers(poolB)
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if _, ok := poolA.PickPeer(fmt.Sprintf("key%d", i)); !ok {
+			key = fmt.Sprintf("key%d", i) // 归属A的key
+		}
+	}
+
+	// A下线中把key转交给B，B的哈希环仍认为归属A，但转发来的请求只在B本地加载
+	poolA.SetDraining(true)
+	done := make(chan struct{})
+	var view gocachex.ByteView
+	var err error
+	go func() {
+		defer close(done)
+		view, err = groupA.Get(key)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request is forwarded back and forth between the nodes")
+	}
+	if err != nil || view.String() != "b:"+key {
+		t.Fatalf("expect B to load %s locally, got %q (err=%v)", key, view.String(), err)
+	}
+	if loadsA.Load() != 0 || loadsB.Load() != 1 {
+		t.Fatalf("expect a single load on B, got A=%d B=%d", loadsA.Load(), loadsB.Load())
+	}
+
+	// B上的请求按B的哈希环转给A，A虽然下线中，仍在本地处理转发来的请求
+	other := ""
+	for i := 0; other == ""; i++ {
+		if _, ok := poolB.PickPeer(fmt.Sprintf("other%d", i)); ok {
+			other = fmt.Sprintf("other%d", i) // 按B的哈希环归属A的key
+		}
+	}
+	if view, err := groupB.Get(other); err != nil || view.String() != "a:"+other {
+		t.Fatalf("expect A to load %s locally, got %q (err=%v)", other, view.String(), err)
+	}
+}
+
+func TestHTTPPoolVNodeFormat(t *testing.T) {
+	self, other := "http://localhost:8021", "http://localhost:8022"
+	// 早期版本的哈希环，滚动升级期间新节点需要与它保持相同的归属
+	legacy := consistenthash.NewMap(50, nil)
+	legacy.SetVNodeFormat(consistenthash.LegacyVNodeFormat)
+	legacy.Add(self, other)
+
+	pool := gocachex.NewHTTPPool(self)
+	pool.SetVNodeFormat(consistenthash.LegacyVNodeFormat)
+	pool.Set(self, other)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, remote := pool.PickPeer(key); remote != (legacy.Get(key) == other) {
+			t.Fatalf("%s: pool and legacy ring disagree on the owner", key)
+		}
+	}
+}
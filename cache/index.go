@@ -0,0 +1,90 @@
+package gocachex
+
+import "sort"
+
+// ValueIndexer 为缓存项生成索引词，例如从JSON值中提取的用户ID或租户名
+type ValueIndexer func(key string, v ByteView) []string
+
+// valueIndex 是从索引词到缓存键的二级索引
+type valueIndex struct {
+	fn    ValueIndexer
+	terms map[string]map[string]struct{} // 索引词 -> 缓存键集合
+	byKey map[string][]string            // 缓存键 -> 它的索引词，用于淘汰时删除
+}
+
+// WithValueIndexer 开启值的二级索引，配合Group.KeysByIndex按值的属性查找缓存键，
+// 用于精确键之外的定向失效。每次写入缓存时在持有缓存锁的情况下调用fn，
+// 因此fn必须足够快且不能调用Group的方法；索引额外保存每个缓存项的键和索引词，
+// 内存开销约为键与索引词的大小之和，并随缓存项的写入和淘汰同步更新
+func WithValueIndexer(fn ValueIndexer) GroupOption {
+	return func(g *Group) {
+		g.mainCache.index = newValueIndex(fn)
+	}
+}
+
+func newValueIndex(fn ValueIndexer) *valueIndex {
+	return &valueIndex{
+		fn:    fn,
+		terms: make(map[string]map[string]struct{}),
+		byKey: make(map[string][]string),
+	}
+}
+
+// add 为缓存项建立索引，覆盖已有缓存项时先删除旧的索引词
+func (idx *valueIndex) add(key string, v ByteView) {
+	idx.addTerms(key, idx.fn(key, v))
+}
+
+// addTerms 将缓存项的索引词设置为terms，替换已有的索引词
+func (idx *valueIndex) addTerms(key string, terms []string) {
+	idx.remove(key)
+	if len(terms) == 0 {
+		return
+	}
+	for _, term := range terms {
+		keys, ok := idx.terms[term]
+		if !ok {
+			keys = make(map[string]struct{})
+			idx.terms[term] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	idx.byKey[key] = terms
+}
+
+// remove 删除缓存项的所有索引词
+func (idx *valueIndex) remove(key string) {
+	for _, term := range idx.byKey[key] {
+		delete(idx.terms[term], key)
+		if len(idx.terms[term]) == 0 {
+			delete(idx.terms, term)
+		}
+	}
+	delete(idx.byKey, key)
+}
+
+// lookup 返回带有索引词term的所有缓存键，按字典序排列
+func (idx *valueIndex) lookup(term string) []string {
+	keys := make([]string, 0, len(idx.terms[term]))
+	for key := range idx.terms[term] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keysByIndex 返回带有索引词term的缓存键，未开启索引时返回nil
+func (c *cache) keysByIndex(term string) []string {
+	c.lock()
+	defer c.unlock()
+	if c.index == nil {
+		return nil
+	}
+	return c.index.lookup(term)
+}
+
+// KeysByIndex 返回本地缓存中索引词为term的所有键，按字典序排列
+// 需要通过WithValueIndexer开启索引，否则返回nil。结果只反映本节点的缓存
+func (g *Group) KeysByIndex(term string) []string {
+	return g.mainCache.keysByIndex(term)
+}
@@ -0,0 +1,81 @@
+package gocachex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keySeparator 是组合键各部分之间的分隔符，keyEscape 用于转义部分内容中的分隔符和转义符本身
+const (
+	keySeparator = ':'
+	keyEscape    = '\\'
+)
+
+// Key 是由多个部分组成的结构化缓存键，例如 NewKey("user", id).Field("profile")
+// String 生成的字符串是规范且无歧义的：各部分中的 ':' 和 '\' 都会被转义，
+// 因此 NewKey("user", "a:b") 与 NewKey("user", "a").Field("b") 不会冲突。
+// 同一个字符串同时用于路由、存储和getter，getter可以通过 ParseKey 还原各部分
+type Key struct {
+	parts []string
+}
+
+// NewKey 由若干部分创建一个结构化键
+func NewKey(parts ...string) Key {
+	return Key{parts: append([]string(nil), parts...)}
+}
+
+// Field 返回追加了一个部分的新键，原键不受影响
+func (k Key) Field(name string) Key {
+	parts := make([]string, 0, len(k.parts)+1)
+	parts = append(parts, k.parts...)
+	return Key{parts: append(parts, name)}
+}
+
+// Parts 返回键的各个部分
+func (k Key) Parts() []string {
+	return append([]string(nil), k.parts...)
+}
+
+// String 返回规范的字符串形式
+func (k Key) String() string {
+	var sb strings.Builder
+	for i, part := range k.parts {
+		if i > 0 {
+			sb.WriteByte(keySeparator)
+		}
+		for j := 0; j < len(part); j++ {
+			if c := part[j]; c == keySeparator || c == keyEscape {
+				sb.WriteByte(keyEscape)
+			}
+			sb.WriteByte(part[j])
+		}
+	}
+	return sb.String()
+}
+
+// ParseKey 将String生成的字符串还原为结构化键
+func ParseKey(s string) (Key, error) {
+	var parts []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case keyEscape:
+			if i+1 >= len(s) {
+				return Key{}, fmt.Errorf("gocachex: dangling escape in key %q", s)
+			}
+			i++
+			sb.WriteByte(s[i])
+		case keySeparator:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return Key{parts: append(parts, sb.String())}, nil
+}
+
+// GetKey 使用结构化键获取缓存值，等价于 Get(k.String())
+func (g *Group) GetKey(k Key) (ByteView, error) {
+	return g.Get(k.String())
+}
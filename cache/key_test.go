@@ -0,0 +1,61 @@
+package gocachex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeyEscaping(t *testing.T) {
+	tests := []struct {
+		key    Key
+		expect string
+	}{
+		{NewKey("user", "123").Field("profile"), "user:123:profile"},
+		{NewKey("user", "a:b"), `user:a\:b`},
+		{NewKey("user", "a").Field("b"), "user:a:b"},
+		{NewKey("user", `a\`).Field("b"), `user:a\\:b`},
+		{NewKey("user", `a\:b`), `user:a\\\:b`},
+		{NewKey("user", "", "x"), "user::x"},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		s := tt.key.String()
+		if s != tt.expect {
+			t.Errorf("expect %q, got %q", tt.expect, s)
+		}
+		if seen[s] {
+			t.Errorf("key %q collides with another key", s)
+		}
+		seen[s] = true
+
+		parsed, err := ParseKey(s)
+		if err != nil || !reflect.DeepEqual(parsed.Parts(), tt.key.Parts()) {
+			t.Errorf("ParseKey(%q) = %q (err=%v), expect %q", s, parsed.Parts(), err, tt.key.Parts())
+		}
+	}
+
+	if _, err := ParseKey(`user:a\`); err == nil {
+		t.Error("expect error for dangling escape")
+	}
+
+	// Field不修改原键
+	base := NewKey("user", "1")
+	_ = base.Field("a")
+	if base.String() != "user:1" {
+		t.Errorf("Field mutated the receiver: %q", base.String())
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	gee := NewGroup("get-key", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			k, err := ParseKey(key)
+			if err != nil {
+				return nil, err
+			}
+			return []byte(k.Parts()[1]), nil
+		}))
+	if view, err := gee.GetKey(NewKey("user", "a:b").Field("profile")); err != nil || view.String() != "a:b" {
+		t.Fatalf("expect a:b, got %q (err=%v)", view.String(), err)
+	}
+}
@@ -0,0 +1,44 @@
+package gocachex
+
+import (
+	"log"
+	"time"
+)
+
+// MetricsSink 接收Group定期推送的统计快照，用于StatsD等推送式监控
+// Flush在后台协程中调用，应尽快返回；返回的错误只记录日志，不影响下一次推送
+type MetricsSink interface {
+	Flush(group string, stats Stats) error
+}
+
+// WithMetricsSink 每隔interval把Group的统计快照推送到sink，Group.Close时再推送最后一次
+func WithMetricsSink(sink MetricsSink, interval time.Duration) GroupOption {
+	return func(g *Group) {
+		if sink != nil && interval > 0 {
+			g.metricsSink = sink
+			g.metricsInterval = interval
+		}
+	}
+}
+
+// flushMetrics 定期推送统计快照，直到Group关闭
+func (g *Group) flushMetrics() {
+	ticker := time.NewTicker(g.metricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.pushMetrics()
+		case <-g.closed:
+			g.pushMetrics()
+			return
+		}
+	}
+}
+
+// pushMetrics 推送一次统计快照
+func (g *Group) pushMetrics() {
+	if err := g.metricsSink.Flush(g.name, g.Stats()); err != nil {
+		log.Println("[GeeCache] failed to flush metrics", err)
+	}
+}
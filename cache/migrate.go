@@ -0,0 +1,96 @@
+// migrate.go 实现运行时切换本地缓存的淘汰策略
+// 切换不需要冷启动：新写入的缓存项进入新策略，读取时两边都查，
+// 后台协程分批把LRU中的缓存项转移到新策略，迁移完成后LRU为空
+package gocachex
+
+import (
+	"errors"
+	"goCacheX/lru"
+)
+
+// errPolicyTTL 表示迁移后的淘汰策略不支持过期时间
+var errPolicyTTL = errors.New("gocachex: migrated eviction policy does not support ttl")
+
+// migrateBatch 是后台迁移每次加锁转移的缓存项数量
+const migrateBatch = 128
+
+// MigratePolicy 将本地缓存的淘汰策略从内置的LRU切换为newPolicy，例如lru.NewSLRU、lru.NewLFU或lru.NewARCPolicy
+// 调用后新写入的缓存项进入newPolicy，读取时先查newPolicy再查LRU，
+// 后台协程按从旧到新的顺序分批把LRU中的缓存项转移到newPolicy，直到LRU为空或Group关闭。
+// 注意：迁移期间两种策略同时持有数据，内存占用最多可达LRU的容量与newPolicy的容量之和，需要为此预留内存。
+// newPolicy的淘汰与LRU的淘汰一样计入统计；热点键、快照、全局内存上限和再平衡清理同时覆盖两边的缓存项。
+// 值去重、二级索引、访问统计和租户配额需要在每次写入时记账，缓冲池的读取器只能引用LRU中的值，
+// 开启这些功能时不能迁移。新策略不支持过期时间，设置了过期时间的缓存项（见Fill.Commit）迁移后只受容量淘汰。
+// 每个Group只能迁移一次
+func (g *Group) MigratePolicy(newPolicy lru.Policy) error {
+	if newPolicy == nil {
+		return errors.New("gocachex: nil eviction policy")
+	}
+	if g.bufferPool {
+		return errors.New("gocachex: cannot migrate eviction policy with a buffer pool")
+	}
+	if err := g.mainCache.setPolicy(newPolicy); err != nil {
+		return err
+	}
+	g.goBackground(g.migrateLoop)
+	return nil
+}
+
+// setPolicy 设置迁移的目标策略，并让它的淘汰与LRU的淘汰一样计入统计
+func (c *cache) setPolicy(p lru.Policy) error {
+	c.lock()
+	defer c.unlock()
+	if c.policy != nil {
+		return errors.New("gocachex: eviction policy already migrated")
+	}
+	if c.dedup != nil || c.index != nil || c.access != nil || c.tenants != nil {
+		return errors.New("gocachex: cannot migrate eviction policy with eviction bookkeeping enabled")
+	}
+	p.SetOnEvicted(func(key string, value lru.Value) {
+		c.noteEvicted(key)
+		c.evictedLocked(key, value.(ByteView))
+	})
+	c.policy = p
+	return nil
+}
+
+// migrateLoop 分批迁移LRU中的缓存项，直到LRU为空或Group关闭
+func (g *Group) migrateLoop() {
+	for g.mainCache.migrateOldest(migrateBatch) {
+		select {
+		case <-g.closed:
+			return
+		default:
+		}
+	}
+}
+
+// migrateOldest 在一次加锁中把LRU中最久未使用的最多n个缓存项转移到新策略，返回LRU中是否还有缓存项
+func (c *cache) migrateOldest(n int) bool {
+	c.lock()
+	defer c.unlock()
+	// 先写入缓冲区中的缓存项，它们会直接进入新策略
+	c.drainLocked()
+	if c.lru == nil {
+		return false
+	}
+	for ; n > 0; n-- {
+		key, value, ok := c.lru.GetOldest()
+		if !ok {
+			return false
+		}
+		c.lru.Take(key)
+		c.policy.Add(key, value)
+	}
+	return c.lru.Len() > 0
+}
+
+// unmigrated 返回LRU中尚未迁移到新策略的缓存项数量
+func (c *cache) unmigrated() int {
+	c.lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.Len()
+}
@@ -0,0 +1,79 @@
+package gocachex
+
+import (
+	"errors"
+	"goCacheX/lru"
+	"sync"
+	"time"
+)
+
+// maxNegativeEntries 是负缓存最多保存的key数，超过时淘汰最久未使用的记录
+const maxNegativeEntries = 10000
+
+// negativeEntry 是一条负缓存记录，保存getter返回的未找到错误
+type negativeEntry struct {
+	err error
+}
+
+// Len 实现lru.Value接口，负缓存按条目数而不是字节数限制
+func (negativeEntry) Len() int { return 0 }
+
+// negativeCache 缓存数据不存在的结果，在ttl内重复请求同一个key不再调用getter
+type negativeCache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	lru *lru.Cache[lru.Value]
+}
+
+// WithNegativeCache 开启负缓存：getter返回包装了ErrNotFound的错误时，在ttl内缓存该错误
+// 不存在的key被反复请求时（例如恶意探测）不会每次都打到后端。负缓存的过期时间独立于缓存值，
+// 通常应设置得较短，因为数据可能很快出现。对该key提交Fill写入值时负缓存记录立即被清除。
+// 过期记录由分组注册表共享的清理器定期删除，不需要每个分组各自启动协程。
+// 只缓存本节点getter的结果，远程节点返回的错误不会被缓存；ttl不大于0时不开启
+func WithNegativeCache(ttl time.Duration) GroupOption {
+	return func(g *Group) {
+		if ttl > 0 {
+			g.negative = &negativeCache{ttl: ttl, lru: lru.NewWithLimits(0, maxNegativeEntries, nil)}
+		}
+	}
+}
+
+// EvictExpired 删除所有已过期的负缓存记录，实现lru.Expirer接口，由分组注册表共享的清理器定期调用
+func (n *negativeCache) EvictExpired() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.lru.EvictExpired()
+}
+
+// get 返回key未过期的负缓存错误，没有记录或未开启负缓存时返回nil
+func (n *negativeCache) get(key string) error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if v, ok := n.lru.Get(key); ok {
+		return v.(negativeEntry).err
+	}
+	return nil
+}
+
+// add 在err表示数据不存在时记录负缓存
+func (n *negativeCache) add(key string, err error) {
+	if n == nil || !errors.Is(err, ErrNotFound) {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lru.AddWithTTL(key, negativeEntry{err: err}, n.ttl)
+}
+
+// remove 删除key的负缓存记录
+func (n *negativeCache) remove(key string) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lru.Delete(key)
+}
@@ -1,6 +1,10 @@
 package gocachex
 
-import pb "goCacheX/gocacheXpb"
+import (
+	"context"
+	"goCacheX/consistenthash"
+	pb "goCacheX/gocacheXpb"
+)
 
 // PeerPicker is the interface that must be implemented to locate
 // the peer that owns a specific key.
@@ -9,8 +13,48 @@ type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
 }
 
+// PeersPicker is an optional interface for pickers that can return
+// several candidate peers for a key, ordered by preference.
+// 按优先级返回多个候选节点：第一个为归属节点，其余为哈希环上的后续节点。
+// 当前节点本身出现在候选中时，应在此截断，由调用方在本地加载
+type PeersPicker interface {
+	PeerPicker
+	PickPeers(key string, n int) []PeerGetter
+}
+
 // PeerGetter is the interface that must be implemented by a peer.
 // 获取节点
 type PeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
+
+// ContextPeerGetter is an optional interface for peers whose requests
+// can be cancelled by a context.
+// ctx结束时请求应尽快返回，超时返回的错误应满足errors.Is(err, context.DeadlineExceeded)
+type ContextPeerGetter interface {
+	PeerGetter
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// ConditionalPeerGetter is an optional interface for peers that can skip
+// resending a value the caller already holds.
+// 条件获取：version与远端当前值的版本一致时不返回数据。
+// 只有已持有本地副本的调用方才能提供version，目前由一致性检查（StartConsistencyCheck）使用；
+// Group的加载只在本地未命中时发生，没有可比较的版本，因此总是发送普通请求
+type ConditionalPeerGetter interface {
+	PeerGetter
+	// GetIfModified 在远端值的版本与version相同时返回modified=false且不填充out
+	GetIfModified(in *pb.Request, out *pb.Response, version string) (modified bool, err error)
+}
+
+// RingChangeFunc is called after the hash ring changes.
+// delta 描述新旧哈希环之间归属变化的范围，self 为当前节点
+type RingChangeFunc func(delta consistenthash.RingDelta, self string)
+
+// RingWatcher is an optional interface for pickers that report
+// changes of the hash ring.
+// 节点变化后通知已注册的函数，例如用于清理本地不再归属当前节点的缓存项
+type RingWatcher interface {
+	PeerPicker
+	OnRingChange(fn RingChangeFunc)
+}
@@ -0,0 +1,126 @@
+package gocachex
+
+import (
+	"bytes"
+	"sync"
+	"unsafe"
+)
+
+// bufferPin 记录缓冲池中一块内存被读取器或正在进行的写入引用的情况
+type bufferPin struct {
+	refs      int  // 尚未Close的读取器数量，加上正在进行的写入
+	evicted   bool // 缓存项已被淘汰，最后一个引用释放时归还缓冲池
+	admitting bool // 值正在写入缓存，加载方和等待者还没有拿到它
+	abandon   bool // 值在写入过程中就被淘汰，内存交给GC回收，不再归还缓冲池
+}
+
+// pin 查询key并为其值的内存增加一个引用，key不存在时返回false
+// 查询与加引用在同一次加锁中完成，期间缓存项不会被淘汰
+func (c *cache) pin(key string) (ByteView, bool) {
+	c.lock()
+	defer c.unlock()
+	if c.lru == nil {
+		return ByteView{}, false
+	}
+	v, ok := c.lru.Peek(key)
+	if !ok || len(v.b) == 0 {
+		return v, ok
+	}
+	c.pinLocked(v.b).refs++
+	return v, true
+}
+
+// pinLocked 返回b的引用记录，不存在时创建，调用方需持有c.mu
+func (c *cache) pinLocked(b []byte) *bufferPin {
+	if c.pins == nil {
+		c.pins = make(map[*byte]*bufferPin)
+	}
+	ptr := unsafe.SliceData(b)
+	p := c.pins[ptr]
+	if p == nil {
+		p = &bufferPin{}
+		c.pins[ptr] = p
+	}
+	return p
+}
+
+// hold 在值写入缓存之前为其内存增加一个引用
+// 值可能在写入的过程中就被淘汰（超过cacheBytes、全局内存上限等），而加载方和所有等待者
+// 随后仍会拿到这个值，此时内存不能归还缓冲池，只能交给GC回收
+func (c *cache) hold(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	p := c.pinLocked(b)
+	p.refs++
+	p.admitting = true
+}
+
+// unhold 在写入完成后释放hold增加的引用
+func (c *cache) unhold(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	if p := c.pins[unsafe.SliceData(b)]; p != nil {
+		p.admitting = false
+		c.unrefLocked(b, p)
+	}
+}
+
+// unpin 释放pin增加的引用，缓存项已被淘汰且没有其他引用时归还缓冲池
+func (c *cache) unpin(v ByteView) {
+	if len(v.b) == 0 {
+		return
+	}
+	c.lock()
+	defer c.unlock()
+	if p := c.pins[unsafe.SliceData(v.b)]; p != nil {
+		c.unrefLocked(v.b, p)
+	}
+}
+
+// unrefLocked 释放b的一个引用，缓存项已被淘汰且没有其他引用时归还缓冲池，调用方需持有c.mu
+func (c *cache) unrefLocked(b []byte, p *bufferPin) {
+	if p.refs--; p.refs == 0 {
+		delete(c.pins, unsafe.SliceData(b))
+		if p.evicted && !p.abandon {
+			putBuffer(b)
+		}
+	}
+}
+
+// releaseBuffer 在缓存项被淘汰时归还其内存，仍被读取器引用时推迟到最后一次Close，
+// 在写入过程中被淘汰时不归还，调用方需持有c.mu
+func (c *cache) releaseBuffer(b []byte) {
+	if len(b) > 0 {
+		if p := c.pins[unsafe.SliceData(b)]; p != nil {
+			p.evicted = true
+			if p.admitting {
+				p.abandon = true
+			}
+			return
+		}
+	}
+	putBuffer(b)
+}
+
+// pinnedReader 读取缓冲池中的缓存值，Close时释放对内存的引用
+type pinnedReader struct {
+	*bytes.Reader
+	c    *cache
+	v    ByteView
+	once sync.Once
+}
+
+// Close 释放对内存的引用，之后的读取返回io.EOF，可以重复调用
+func (r *pinnedReader) Close() error {
+	r.once.Do(func() {
+		r.Reader.Reset(nil)
+		r.c.unpin(r.v)
+	})
+	return nil
+}
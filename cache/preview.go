@@ -0,0 +1,50 @@
+package gocachex
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// WithValuePreviewInLogs 在命中日志中附带值的预览，最多maxBytes字节，默认关闭
+// 值可能包含密码、令牌等敏感数据，只应在值不敏感时为调试开启。
+// 预览中可打印的ASCII字符原样输出，其余字节转义为\xNN，超出长度的部分被截断并注明总长度
+func WithValuePreviewInLogs(maxBytes int) GroupOption {
+	return func(g *Group) {
+		g.previewMax = maxBytes
+	}
+}
+
+// logHit 记录一次缓存命中，开启值预览时附带键和值的预览
+func (g *Group) logHit(key string, v ByteView) {
+	if g.previewMax <= 0 {
+		log.Println("[GeeCache] hit")
+		return
+	}
+	log.Printf("[GeeCache] hit %s: %s", key, previewValue(v, g.previewMax))
+}
+
+// previewValue 返回值的前max个字节的转义表示
+func previewValue(v ByteView, max int) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i, c := range v.b {
+		if i == max {
+			break
+		}
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	sb.WriteByte('"')
+	if v.Len() > max {
+		fmt.Fprintf(&sb, "... (%d bytes)", v.Len())
+	}
+	return sb.String()
+}
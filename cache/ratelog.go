@@ -0,0 +1,67 @@
+package gocachex
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultPeerErrorLogInterval 是同一节点两次错误日志之间的默认最小间隔
+const defaultPeerErrorLogInterval = 5 * time.Second
+
+// maxTrackedErrors 是限流日志最多跟踪的不同来源数，超过时清空重新计数
+const maxTrackedErrors = 1024
+
+// rateLimitedLog 按来源对日志限流：同一来源每个interval内只输出一次，并附带期间被抑制的次数
+// 节点宕机时每个请求都会失败，不限流会产生大量重复日志。错误信息通常包含请求的key，
+// 因此按来源（例如节点地址）而不是按错误内容去重
+type rateLimitedLog struct {
+	mu       sync.Mutex
+	interval time.Duration
+	seen     map[string]*logState
+}
+
+// logState 记录一条日志最近一次输出的时间和之后被抑制的次数
+type logState struct {
+	last       time.Time
+	suppressed int
+}
+
+// println 输出一条来自source的日志，同一来源在interval内重复时只计数不输出
+func (l *rateLimitedLog) println(source, prefix, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen == nil || len(l.seen) >= maxTrackedErrors {
+		l.seen = make(map[string]*logState)
+	}
+	now := time.Now()
+	st, ok := l.seen[source]
+	if ok && now.Sub(st.last) < l.interval {
+		st.suppressed++
+		return
+	}
+	if ok && st.suppressed > 0 {
+		log.Printf("%s %s (repeated %d times since last report)", prefix, msg, st.suppressed)
+	} else {
+		log.Println(prefix, msg)
+	}
+	l.seen[source] = &logState{last: now}
+}
+
+// WithPeerErrorLogInterval 设置同一节点远程获取错误的日志间隔，默认5秒
+// 节点宕机时它的错误在interval内只记录一次，下一次记录时附带期间重复的次数；
+// interval不大于0时每次失败都记录
+func WithPeerErrorLogInterval(interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.peerErrLog.interval = interval
+	}
+}
+
+// peerName 返回用于区分节点的名称，节点实现fmt.Stringer时使用String
+func peerName(peer PeerGetter) string {
+	if s, ok := peer.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T %v", peer, peer)
+}
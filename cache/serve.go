@@ -0,0 +1,52 @@
+package gocachex
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeByteView 将缓存值写入HTTP响应，支持Range请求
+// 对Range请求返回206 Partial Content及Content-Range，范围无法满足时返回416，
+// 并始终设置Accept-Ranges: bytes，便于客户端对音视频等大对象进行拖动播放。
+// 数据通过ByteView.Reader按需读取，不会复制整个值；未设置Content-Type时使用application/octet-stream
+func ServeByteView(w http.ResponseWriter, r *http.Request, v ByteView) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, "", time.Time{}, v.Reader())
+}
+
+// APIHandler 返回对外提供缓存数据的HTTP处理器，从查询参数key读取键，例如 GET /api?key=Tom
+// 响应携带ETag（值内容的哈希）和Cache-Control: max-age，浏览器或CDN可以据此缓存响应，
+// 请求的If-None-Match与当前ETag一致时返回304而不重复发送数据。maxAge不大于0时设置no-cache，
+// 要求客户端每次都重新验证。缺少key时返回400，数据不存在（ErrNotFound）时返回404，其他错误返回500。
+// 缓存项目前没有修改时间，因此不设置Last-Modified
+func APIHandler(g *Group, maxAge time.Duration) http.Handler {
+	cacheControl := "no-cache"
+	if maxAge > 0 {
+		cacheControl = fmt.Sprintf("max-age=%d", int64(maxAge/time.Second))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if g.normalize(key) == "" {
+			http.Error(w, "key is required", http.StatusBadRequest)
+			return
+		}
+		view, err := g.Get(key)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", view.Version())
+		// ServeContent根据ETag处理If-None-Match等条件请求
+		ServeByteView(w, r, view)
+	})
+}
@@ -0,0 +1,35 @@
+package gocachex
+
+import "math"
+
+// Snapshot 返回本地缓存内容的副本，复制过程中不阻塞并发的读写
+//
+// 实现分两步：先短暂加锁取得键列表，再逐个键短暂加锁复制其值，因此写入方最多只需等待一个键的复制。
+// 代价是快照只是一个模糊的时间点视图：
+//   - 每个值都是某一时刻缓存中的完整值，不会读到写了一半的数据
+//   - 取得键列表之后被淘汰的键不在快照中，新写入的键也不在快照中
+//   - 不同键的值可能来自不同时刻，快照期间被更新的键可能是旧值也可能是新值
+//
+// 复制不改变LRU的访问顺序，也不计入命中统计
+func (g *Group) Snapshot() map[string]ByteView {
+	keys := g.mainCache.oldestN(math.MaxInt)
+	snap := make(map[string]ByteView, len(keys))
+	for _, key := range keys {
+		if v, ok := g.mainCache.clone(key); ok {
+			snap[key] = v
+		}
+	}
+	return snap
+}
+
+// clone 在持有锁时复制key对应的值，key不存在时返回false，不改变访问顺序
+// 在锁内复制可以保证开启WithBufferPool时值的内存不会在复制过程中被复用
+func (c *cache) clone(key string) (ByteView, bool) {
+	c.lock()
+	defer c.unlock()
+	v, ok := c.peekLocked(key)
+	if !ok {
+		return ByteView{}, false
+	}
+	return ByteView{b: cloneBytes(v.b)}, true
+}
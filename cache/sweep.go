@@ -0,0 +1,116 @@
+package gocachex
+
+import (
+	"goCacheX/consistenthash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rebalanceSweep 记录等待处理的哈希环变化
+type rebalanceSweep struct {
+	batch    int           // 每批检查的键数量
+	interval time.Duration // 两批之间的间隔
+
+	mu      sync.Mutex
+	pending []ringChange  // 尚未处理的哈希环变化，按发生顺序排列
+	wake    chan struct{} // 有新的变化时通知后台协程，容量为1
+
+	evictions atomic.Int64 // 因归属其他节点被清理的缓存项数量
+}
+
+// ringChange 是一次哈希环变化
+type ringChange struct {
+	delta consistenthash.RingDelta
+	self  string
+}
+
+// WithRebalanceSweep 开启节点变化后的本地清理
+// 注册的PeerPicker实现RingWatcher接口（例如HTTPPool）时，每次哈希环重建后，
+// 后台协程根据新旧哈希环的变化清理本地缓存中归属已转移到其他节点的缓存项，释放内存并避免日后读到过期数据。
+// 清理分批进行：每批在一次加锁中最多检查batch个键，两批之间等待interval，不会长时间阻塞读写。
+// 清理与请求处理互不影响：清理完成之前，当前节点仍可能用本地缓存响应已归属其他节点的key
+func WithRebalanceSweep(batch int, interval time.Duration) GroupOption {
+	return func(g *Group) {
+		if batch > 0 {
+			g.sweep = &rebalanceSweep{
+				batch:    batch,
+				interval: interval,
+				wake:     make(chan struct{}, 1),
+			}
+		}
+	}
+}
+
+// notify 记录一次哈希环变化，在HTTPPool持有锁时调用，不会阻塞
+func (s *rebalanceSweep) notify(delta consistenthash.RingDelta, self string) {
+	s.mu.Lock()
+	s.pending = append(s.pending, ringChange{delta, self})
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take 取出所有等待处理的哈希环变化
+func (s *rebalanceSweep) take() []ringChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := s.pending
+	s.pending = nil
+	return changes
+}
+
+// evicted 返回被清理的缓存项数量，s为nil时返回0
+func (s *rebalanceSweep) evicted() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.evictions.Load()
+}
+
+// lost 判断经过changes中的各次变化后，key是否归属其他节点
+// 按顺序应用每次变化，key先转出又转回当前节点时不清理
+func lost(changes []ringChange, key string) bool {
+	lost := false
+	for _, c := range changes {
+		if mv, ok := c.delta.Moved(key); ok {
+			lost = mv.To != c.self
+		}
+	}
+	return lost
+}
+
+// sweepLoop 在哈希环变化后清理本地缓存，直到Group关闭
+func (g *Group) sweepLoop() {
+	for {
+		select {
+		case <-g.sweep.wake:
+			g.sweepOnce(g.sweep.take())
+		case <-g.closed:
+			return
+		}
+	}
+}
+
+// sweepOnce 分批清理changes中转移到其他节点的缓存项，Group关闭时提前返回
+func (g *Group) sweepOnce(changes []ringChange) {
+	if len(changes) == 0 {
+		return
+	}
+	s := g.sweep
+	keys := g.mainCache.keys()
+	for start := 0; start < len(keys); start += s.batch {
+		if start > 0 {
+			select {
+			case <-time.After(s.interval):
+			case <-g.closed:
+				return
+			}
+		}
+		batch := keys[start:min(start+s.batch, len(keys))]
+		n := g.mainCache.removeIf(batch, func(key string) bool { return lost(changes, key) })
+		s.evictions.Add(int64(n))
+	}
+}
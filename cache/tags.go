@@ -0,0 +1,70 @@
+// tags.go 实现了基于标签的失效
+// 一个缓存值通常由多条源数据派生而来，写入时为它标记这些源数据的标签，
+// 任何一条源数据变化时，通过InvalidateTag一次删除所有带有该标签的缓存项
+package gocachex
+
+import (
+	"errors"
+	"time"
+)
+
+// SetWithTags 将value写入本地缓存并为它标记tags，ttl大于0时缓存项在ttl后过期
+// 写入会复制value，并清除key的负缓存记录。再次写入同一个key（包括加载）会替换它的标签。
+// 标签索引额外保存每个带标签的缓存项的键和标签，内存开销约为键与标签的大小之和；
+// 缓存项被淘汰、过期或删除时它的标签随之删除，不会残留在索引中。
+// 迁移淘汰策略（见MigratePolicy）后ttl大于0返回错误。写入只影响本节点的缓存
+func (g *Group) SetWithTags(key string, value []byte, ttl time.Duration, tags ...string) error {
+	key = g.normalize(key)
+	if key == "" {
+		return errors.New("key is required")
+	}
+	g.negative.remove(key)
+	if err := g.mainCache.addTagged(key, ByteView{b: cloneBytes(value)}, ttl, tags); err != nil {
+		return err
+	}
+	enforceGlobalMaxBytes()
+	return nil
+}
+
+// InvalidateTag 删除本地缓存中所有带有标签tag的缓存项，返回删除的数量
+// 与Delete一样，被删除的键进行中的加载不再写入缓存。只影响本节点的缓存，其他节点需要各自调用
+func (g *Group) InvalidateTag(tag string) int {
+	return g.mainCache.invalidateTag(tag)
+}
+
+// addTagged 在一次加锁中写入缓存项并建立标签索引
+func (c *cache) addTagged(key string, value ByteView, ttl time.Duration, tags []string) error {
+	c.lock()
+	defer c.unlock()
+	if ttl > 0 && c.policy != nil {
+		return errPolicyTTL
+	}
+	// 先写入缓冲区中的缓存项，避免它们随后覆盖本次写入及其标签
+	c.drainLocked()
+	c.addLockedTTL(key, value, ttl)
+	if _, ok := c.peekLocked(key); !ok || len(tags) == 0 {
+		return nil // 值过大被立即淘汰时不再记录
+	}
+	if c.tags == nil {
+		c.tags = newValueIndex(nil)
+	}
+	c.tags.addTerms(key, tags)
+	return nil
+}
+
+// invalidateTag 在一次加锁中删除带有标签tag的所有缓存项
+func (c *cache) invalidateTag(tag string) int {
+	c.lock()
+	defer c.unlock()
+	c.drainLocked()
+	if c.tags == nil {
+		return 0
+	}
+	n := 0
+	for _, key := range c.tags.lookup(tag) {
+		if c.removeLocked(key) {
+			n++
+		}
+	}
+	return n
+}
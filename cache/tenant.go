@@ -0,0 +1,141 @@
+package gocachex
+
+import (
+	"goCacheX/lru"
+	"strings"
+)
+
+// TenantStats 是一个租户在本地缓存中的使用情况
+type TenantStats struct {
+	Bytes     int64 // 该租户的缓存项占用的内存（字节），包括键和值
+	Len       int   // 该租户的缓存项数量
+	Quota     int64 // 该租户的内存配额，0表示不限制
+	Evictions int64 // 因超过配额被淘汰的缓存项数量
+}
+
+// tenantQuotas 按租户限制缓存占用的内存
+// 每个租户用一个只保存键和大小的LRU记录自己的缓存项及访问顺序，
+// 租户超过配额时只淘汰该租户最久未使用的缓存项
+type tenantQuotas struct {
+	sep          string
+	quotas       map[string]int64
+	defaultQuota int64
+	parts        map[string]*tenantPart
+}
+
+// tenantPart 是一个租户的分区
+type tenantPart struct {
+	keys      *lru.Cache[int] // 键到缓存项大小的映射，Bytes即该租户的内存占用
+	evictions int64
+}
+
+// WithTenantQuotas 开启按租户的内存配额
+// key中第一个sep之前的部分是租户名，不含sep的key属于名为""的租户。quotas指定各租户的配额，
+// 未列出的租户使用defaultQuota，配额为0表示不限制。某个租户超过配额时只淘汰它自己最久未使用的缓存项，
+// 其他租户的数据不受影响；cacheBytes仍然是所有租户合计的上限，超过时按整体的LRU顺序淘汰。
+// 由于路由不感知租户，集群内所有节点的同名Group必须配置相同的配额
+func WithTenantQuotas(sep string, quotas map[string]int64, defaultQuota int64) GroupOption {
+	return func(g *Group) {
+		t := &tenantQuotas{
+			sep:          sep,
+			quotas:       make(map[string]int64, len(quotas)),
+			defaultQuota: defaultQuota,
+			parts:        make(map[string]*tenantPart),
+		}
+		for tenant, quota := range quotas {
+			t.quotas[tenant] = quota
+		}
+		g.mainCache.tenants = t
+	}
+}
+
+// tenantOf 返回key所属的租户
+func (t *tenantQuotas) tenantOf(key string) string {
+	tenant, _, ok := strings.Cut(key, t.sep)
+	if !ok {
+		return ""
+	}
+	return tenant
+}
+
+// quota 返回租户的配额
+func (t *tenantQuotas) quota(tenant string) int64 {
+	if q, ok := t.quotas[tenant]; ok {
+		return q
+	}
+	return t.defaultQuota
+}
+
+// part 返回租户的分区，不存在时创建
+func (t *tenantQuotas) part(tenant string) *tenantPart {
+	p, ok := t.parts[tenant]
+	if !ok {
+		p = &tenantPart{keys: lru.NewWithSizer(0, func(n int) int { return n }, nil)}
+		t.parts[tenant] = p
+	}
+	return p
+}
+
+// added 记录写入LRU的缓存项，size为值的大小
+func (t *tenantQuotas) added(key string, size int) {
+	t.part(t.tenantOf(key)).keys.Add(key, size)
+}
+
+// enforceTenantQuota 淘汰key所属租户超出配额的缓存项，从该租户最久未使用的开始，调用方需持有c.mu
+func (c *cache) enforceTenantQuota(key string) {
+	t := c.tenants
+	tenant := t.tenantOf(key)
+	p, quota := t.parts[tenant], t.quota(tenant)
+	for p != nil && quota > 0 && p.keys.Bytes() > quota {
+		k, _, _ := p.keys.GetOldest()
+		p.evictions++
+		c.noteEvicted(k)
+		// 从LRU删除时淘汰回调会同时删除分区中的记录
+		if !c.lru.Delete(k) {
+			p.keys.Delete(k)
+		}
+	}
+}
+
+// touch 记录一次命中
+func (t *tenantQuotas) touch(key string) {
+	if p, ok := t.parts[t.tenantOf(key)]; ok {
+		p.keys.Get(key)
+	}
+}
+
+// removed 在缓存项离开LRU时删除记录
+func (t *tenantQuotas) removed(key string) {
+	tenant := t.tenantOf(key)
+	if p, ok := t.parts[tenant]; ok {
+		p.keys.Delete(key)
+		if p.keys.Len() == 0 && p.evictions == 0 {
+			delete(t.parts, tenant)
+		}
+	}
+}
+
+// stats 返回所有租户的使用情况
+func (t *tenantQuotas) stats() map[string]TenantStats {
+	stats := make(map[string]TenantStats, len(t.parts))
+	for tenant, p := range t.parts {
+		stats[tenant] = TenantStats{
+			Bytes:     p.keys.Bytes(),
+			Len:       p.keys.Len(),
+			Quota:     t.quota(tenant),
+			Evictions: p.evictions,
+		}
+	}
+	return stats
+}
+
+// TenantStats 返回各租户在本地缓存中的使用情况，未开启WithTenantQuotas时返回nil
+func (g *Group) TenantStats() map[string]TenantStats {
+	c := &g.mainCache
+	c.lock()
+	defer c.unlock()
+	if c.tenants == nil {
+		return nil
+	}
+	return c.tenants.stats()
+}
@@ -0,0 +1,192 @@
+// warmup.go 实现了新节点加入集群时的预热
+// 新节点加入后会接管哈希环上的一部分key，但本地缓存是空的，会引发一波未命中。
+// 预热时新节点把自己在哈希环上负责的范围发给其他节点，由对方只返回落在这些范围内的热点键，
+// 再以有限的并发从原节点拉取数据写入本地缓存。预热是尽力而为的：任何失败都只记录日志，
+// 不影响节点正常提供服务。
+package gocachex
+
+import (
+	"encoding/json"
+	"fmt"
+	"goCacheX/consistenthash"
+	pb "goCacheX/gocacheXpb"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultHotKeys 是热点键列表接口默认返回的键数量
+const defaultHotKeys = 1000
+
+// SetServeHotKeys 开启或关闭热点键列表接口 GET <basePath>_hotkeys/<group>?limit=N&ranges=S-E,...
+// 该接口返回本节点最近使用的键（不含值），供新加入的节点预热，默认关闭。
+// ranges为可选的哈希范围列表 (S, E]，只返回哈希值落在其中的键，
+// 哈希值由本节点的哈希环计算，因此要求各节点使用相同的哈希函数和盐值
+func (p *HTTPPool) SetServeHotKeys(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hotKeys = enabled
+}
+
+// serveHotKeys 以JSON数组返回指定Group最近使用的键，按从新到旧排列
+func (p *HTTPPool) serveHotKeys(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path[len(p.basePath)+len(hotKeysPrefix):]
+	group := GetGroup(name)
+	if group == nil {
+		http.Error(w, "no such group: "+name, http.StatusNotFound)
+		return
+	}
+	limit := defaultHotKeys
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, "bad limit: "+s, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	keys := group.mainCache.newestN(limit)
+	if s := r.URL.Query().Get("ranges"); s != "" {
+		ranges, err := parseHashRanges(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		p.mu.Lock()
+		ring := p.peers
+		p.mu.Unlock()
+		if ring == nil {
+			http.Error(w, "peers not set", http.StatusServiceUnavailable)
+			return
+		}
+		keys = group.mainCache.newestMatching(limit, func(key string) bool {
+			hash := ring.KeyHash(key)
+			for _, r := range ranges {
+				if r.Contains(hash) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// formatHashRanges 将哈希范围编码为 S-E,S-E 的形式
+func formatHashRanges(ranges []consistenthash.HashRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseHashRanges 解析formatHashRanges编码的哈希范围
+func parseHashRanges(s string) ([]consistenthash.HashRange, error) {
+	var ranges []consistenthash.HashRange
+	for _, part := range strings.Split(s, ",") {
+		start, end, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("bad range: %q", part)
+		}
+		s, err1 := strconv.ParseUint(start, 10, 32)
+		e, err2 := strconv.ParseUint(end, 10, 32)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("bad range: %q", part)
+		}
+		ranges = append(ranges, consistenthash.HashRange{Start: uint32(s), End: uint32(e)})
+	}
+	return ranges, nil
+}
+
+// Warm 从其他节点预热group中归属于本节点的key，返回成功写入本地缓存的数量
+// limit为向每个节点请求的、归属本节点的热点键数量上限，parallelism为同时拉取数据的并发数。
+// 需要先调用Set设置节点列表，并且其他节点开启了SetServeHotKeys
+func (p *HTTPPool) Warm(group *Group, limit, parallelism int) int {
+	p.mu.Lock()
+	ring := p.peers
+	getters := make(map[string]*httpGetter, len(p.httpGetters))
+	for peer, getter := range p.httpGetters {
+		if peer != p.self {
+			getters[peer] = getter
+		}
+	}
+	p.mu.Unlock()
+	if ring == nil {
+		return 0
+	}
+	owned := ring.Owned(p.self)
+	if len(owned) == 0 {
+		return 0
+	}
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		warmed int
+		wg     sync.WaitGroup
+	)
+	sem := make(chan struct{}, parallelism)
+	for peer, getter := range getters {
+		keys, err := getter.hotKeys(group.name, limit, owned)
+		if err != nil {
+			p.Log("warm: failed to list hot keys from %s: %v", peer, err)
+			continue
+		}
+		for _, key := range keys {
+			if ring.Get(key) != p.self {
+				continue
+			}
+			if _, ok := group.mainCache.get(key); ok {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(getter *httpGetter, key string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				res := &pb.Response{}
+				if err := getter.Get(&pb.Request{Group: group.name, Key: key}, res); err != nil {
+					p.Log("warm: failed to get %s: %v", key, err)
+					return
+				}
+				group.populateCache(key, ByteView{b: res.Value})
+				mu.Lock()
+				warmed++
+				mu.Unlock()
+			}(getter, key)
+		}
+	}
+	wg.Wait()
+	return warmed
+}
+
+// hotKeys 请求远端节点的热点键列表，ranges不为空时只返回哈希值落在其中的键
+func (h *httpGetter) hotKeys(group string, limit int, ranges []consistenthash.HashRange) ([]string, error) {
+	u := fmt.Sprintf("%v%v%v?limit=%d", h.baseURL, hotKeysPrefix, url.QueryEscape(group), limit)
+	if len(ranges) > 0 {
+		u += "&ranges=" + formatHashRanges(ranges)
+	}
+	client := h.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned: %v", res.Status)
+	}
+	var keys []string
+	if err := json.NewDecoder(res.Body).Decode(&keys); err != nil {
+		return nil, fmt.Errorf("decoding response body: %v", err)
+	}
+	return keys, nil
+}
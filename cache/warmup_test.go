@@ -0,0 +1,132 @@
+package gocachex
+
+import (
+	"encoding/json"
+	"fmt"
+	pb "goCacheX/gocacheXpb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestServeHotKeys(t *testing.T) {
+	gee := NewGroup("hot-keys", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	for _, k := range []string{"a", "b", "c"} {
+		gee.Get(k)
+	}
+	pool := NewHTTPPool("localhost:9999")
+	server := httptest.NewServer(pool)
+	defer server.Close()
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+
+	// 默认关闭
+	if _, err := getter.hotKeys("hot-keys", 2, nil); err == nil {
+		t.Fatal("hot keys endpoint should be disabled by default")
+	}
+	pool.SetServeHotKeys(true)
+	keys, err := getter.hotKeys("hot-keys", 2, nil)
+	if err != nil || strings.Join(keys, ",") != "c,b" {
+		t.Fatalf("expect [c b], got %v (err=%v)", keys, err)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	// 已有节点：提供热点键列表和数据
+	existing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, defaultBasePath)
+		if strings.HasPrefix(path, hotKeysPrefix) {
+			var keys []string
+			for i := 0; i < 100; i++ {
+				keys = append(keys, fmt.Sprintf("key%d", i))
+			}
+			json.NewEncoder(w).Encode(keys)
+			return
+		}
+		key := path[strings.Index(path, "/")+1:]
+		body, _ := proto.Marshal(&pb.Response{Value: []byte("remote-" + key)})
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer existing.Close()
+
+	var getterCalls int
+	gee := NewGroup("warm", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) {
+			getterCalls++
+			return []byte("local-" + key), nil
+		}))
+
+	self := "http://joining-node"
+	pool := NewHTTPPool(self)
+	pool.Set(self, existing.URL)
+	warmed := pool.Warm(gee, 100, 4)
+	if warmed == 0 || warmed == 100 {
+		t.Fatalf("expect only the owned slice of keys to be warmed, got %d", warmed)
+	}
+
+	// 归属本节点的key已预热，直接命中且值来自原节点
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		_, cached := gee.mainCache.get(key)
+		if owned := pool.peers.Get(key) == self; owned != cached {
+			t.Fatalf("key %s: owned=%v cached=%v", key, owned, cached)
+		}
+		if cached {
+			if view, _ := gee.Get(key); view.String() != "remote-"+key {
+				t.Fatalf("key %s: unexpected warmed value %q", key, view.String())
+			}
+		}
+	}
+	if getterCalls != 0 {
+		t.Fatalf("warmed keys should not invoke the getter, got %d calls", getterCalls)
+	}
+}
+
+func TestWarmOwnedRanges(t *testing.T) {
+	// 原节点缓存了大量的key，只请求少量热点键时也应全部归属新节点
+	src := NewGroup("warm-ranges-src", 64<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("remote-" + key), nil }))
+	for i := 0; i < 200; i++ {
+		src.Get(fmt.Sprintf("key%d", i))
+	}
+	var srcPool *HTTPPool
+	existing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.Replace(r.URL.Path, "warm-ranges-dst", "warm-ranges-src", 1)
+		srcPool.ServeHTTP(w, r)
+	}))
+	defer existing.Close()
+	self := "http://joining-node"
+	srcPool = NewHTTPPool(existing.URL)
+	srcPool.SetServeHotKeys(true)
+	srcPool.Set(self, existing.URL)
+
+	dst := NewGroup("warm-ranges-dst", 64<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte("local-" + key), nil }))
+	pool := NewHTTPPool(self)
+	pool.Set(self, existing.URL)
+	if warmed := pool.Warm(dst, 10, 2); warmed != 10 {
+		t.Fatalf("expect 10 owned keys to be warmed, got %d", warmed)
+	}
+	for _, key := range dst.mainCache.keys() {
+		if pool.peers.Get(key) != self {
+			t.Fatalf("warmed key %s is not owned by the joining node", key)
+		}
+	}
+
+	getter := &httpGetter{baseURL: existing.URL + defaultBasePath}
+	if _, err := getter.hotKeys("warm-ranges-src", 10, nil); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get(existing.URL + defaultBasePath + hotKeysPrefix + "warm-ranges-src?ranges=1-x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expect 400 for a bad range, got %d", res.StatusCode)
+	}
+}
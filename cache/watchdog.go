@@ -0,0 +1,51 @@
+package gocachex
+
+import (
+	"log"
+	"runtime"
+	"time"
+)
+
+// StuckFunc 在缓存锁被持有超过阈值时调用，held为当前已持有的时长
+type StuckFunc func(group string, held time.Duration)
+
+// WithLockWatchdog 开启缓存锁看门狗，默认关闭
+// 看门狗在后台定期检查本地缓存的锁，持有时间超过threshold时输出所有协程的调用栈，
+// 并调用onStuck（可以为nil），每次持锁只报告一次。这是排查死锁的安全网：
+// Go的互斥锁无法被其他协程安全地强制释放，onStuck适合用来报警、上报指标或主动退出进程以便重启。
+// 开启后每次加锁都要额外记录时间；看门狗协程在Group.Close时退出
+func WithLockWatchdog(threshold time.Duration, onStuck StuckFunc) GroupOption {
+	return func(g *Group) {
+		if threshold > 0 {
+			g.mainCache.trackLock = true
+			g.lockThreshold = threshold
+			g.onStuck = onStuck
+		}
+	}
+}
+
+// watchLock 定期检查缓存锁的持有时间，直到Group关闭
+func (g *Group) watchLock() {
+	ticker := time.NewTicker(max(g.lockThreshold/4, time.Millisecond))
+	defer ticker.Stop()
+	var reported int64 // 已报告过的持锁起始时间，避免同一次持锁重复报告
+	for {
+		select {
+		case <-ticker.C:
+			at := g.mainCache.lockedAt.Load()
+			held := g.mainCache.heldFor()
+			if at == 0 || at == reported || held < g.lockThreshold {
+				continue
+			}
+			reported = at
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			log.Printf("[GeeCache] group %s: cache lock held for %v, goroutine dump:\n%s", g.name, held, buf)
+			if g.onStuck != nil {
+				g.onStuck(g.name, held)
+			}
+		case <-g.closed:
+			return
+		}
+	}
+}
@@ -3,20 +3,69 @@
 package consistenthash
 
 import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
 	"hash/crc32"
 	"sort"
-	"strconv"
+	"sync"
 )
 
 // Hash 定义哈希函数类型
 type Hash func(data []byte) uint32 //原因是crc32.ChecksumIEEE是这个类型
 
+// 内置哈希函数的注册名
+const (
+	HashCRC32  = "crc32"  // crc32.ChecksumIEEE，默认的哈希函数
+	HashXXHash = "xxhash" // XXHash，需要显式选择，会改变key的归属
+)
+
+var (
+	hashMu sync.RWMutex
+	hashes = map[string]Hash{ // 已注册的具名哈希函数，用于跨进程导入哈希环
+		HashCRC32:  crc32.ChecksumIEEE,
+		HashXXHash: XXHash,
+	}
+)
+
+// RegisterHash 注册一个具名哈希函数
+// 只有使用具名哈希函数的Map导出后，才能在其他进程中通过ImportMap还原
+func RegisterHash(name string, fn Hash) {
+	if name == "" || fn == nil {
+		panic("consistenthash: RegisterHash with empty name or nil hash")
+	}
+	hashMu.Lock()
+	defer hashMu.Unlock()
+	hashes[name] = fn
+}
+
+// lookupHash 根据名称查找已注册的哈希函数
+func lookupHash(name string) (Hash, bool) {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+	fn, ok := hashes[name]
+	return fn, ok
+}
+
+// 虚拟节点名称的格式，参数依次为真实节点名和虚拟节点序号，用法与fmt.Sprintf相同
+const (
+	// DefaultVNodeFormat 在节点名和序号之间加分隔符，节点"1"的第11个虚拟节点与节点"11"的第1个不会重名
+	DefaultVNodeFormat = "%s#%d"
+	// LegacyVNodeFormat 是早期版本使用的格式：序号直接拼接在节点名前面，
+	// 相似的节点名（如"1"和"11"）会生成相同的虚拟节点，导致它们在哈希环上的位置相关
+	LegacyVNodeFormat = "%[2]d%[1]s"
+)
+
 // Map 是一致性哈希算法的主要数据结构
 type Map struct {
 	hash      Hash           // 哈希函数
+	hashName  string         // 哈希函数的注册名，自定义的匿名哈希函数为空
 	nreplicas int            // 虚拟节点倍数
 	keys      []int          // 哈希环上的已排序节点哈希值
 	mapping   map[int]string // 节点哈希值到节点名的映射
+	nodes     []string       // 按添加顺序记录的真实节点
+	salt      []byte         // 可选的盐值，参与所有哈希计算
+	vnodeFmt  string         // 虚拟节点名称的格式
 }
 
 // NewMap 创建一个Map实例
@@ -27,19 +76,88 @@ func NewMap(nreplicas int, hashfunc Hash) *Map {
 		hash:      hashfunc,
 		nreplicas: nreplicas,
 		mapping:   make(map[int]string),
+		vnodeFmt:  DefaultVNodeFormat,
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
+		m.hashName = HashCRC32
 	}
 	return m
 }
 
+// NewSaltedMap 创建一个带盐值的Map
+// 盐值会混入虚拟节点和key的哈希计算，攻击者不知道盐值时无法构造出集中落到同一节点的key。
+// 注意：同一集群的所有节点（以及直接路由的客户端）必须使用相同的盐值，否则对key的归属会产生分歧；
+// 盐值会随Export一起导出。
+func NewSaltedMap(nreplicas int, hashfunc Hash, salt []byte) *Map {
+	m := NewMap(nreplicas, hashfunc)
+	m.SetSalt(salt)
+	return m
+}
+
+// SetSalt 设置哈希盐值，必须在Add之前调用
+func (m *Map) SetSalt(salt []byte) {
+	m.salt = append([]byte(nil), salt...)
+}
+
+// SetVNodeFormat 设置虚拟节点名称的格式，必须在Add之前调用，空字符串表示DefaultVNodeFormat
+// 格式的参数依次为节点名(string)和序号(int)，例如"%s-%d"。需要与早期版本或其他实现
+// 对key的归属保持一致时使用对应的格式，例如LegacyVNodeFormat。格式会随Export一起导出
+func (m *Map) SetVNodeFormat(format string) {
+	if format == "" {
+		format = DefaultVNodeFormat
+	}
+	m.vnodeFmt = format
+}
+
+// RandomSalt 生成一个16字节的随机盐值
+// 通常在集群部署时生成一次并通过配置分发给所有节点
+func RandomSalt() []byte {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic("consistenthash: reading random salt: " + err.Error())
+	}
+	return salt
+}
+
+// hashKey 计算data的哈希值，设置了盐值时先将盐值拼接在前面
+func (m *Map) hashKey(data string) int {
+	if len(m.salt) == 0 {
+		return int(m.hash([]byte(data)))
+	}
+	buf := make([]byte, 0, len(m.salt)+len(data))
+	buf = append(buf, m.salt...)
+	buf = append(buf, data...)
+	return int(m.hash(buf))
+}
+
+// NewMapByName 使用已注册的具名哈希函数创建Map
+// 与NewMap不同，这样创建的Map可以被导出并在其他进程中导入
+func NewMapByName(nreplicas int, hashName string) (*Map, error) {
+	fn, ok := lookupHash(hashName)
+	if !ok {
+		return nil, fmt.Errorf("consistenthash: unknown hash function %q", hashName)
+	}
+	m := NewMap(nreplicas, fn)
+	m.hashName = hashName
+	return m, nil
+}
+
 // Add 添加节点到哈希环
-// 为每个节点创建nreplicas个虚拟节点
+// 为每个节点创建nreplicas个虚拟节点，虚拟节点哈希值相同时由名称较小的节点占据该位置
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
+		m.nodes = append(m.nodes, key)
 		for i := 0; i < m.nreplicas; i++ {
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			hash := m.hashKey(fmt.Sprintf(m.vnodeFmt, key, i))
+			// 哈希冲突时保留名称较小的节点，使结果与节点的添加顺序无关，
+			// 保证所有进程对key的归属判断一致
+			if existing, ok := m.mapping[hash]; ok {
+				if key < existing {
+					m.mapping[hash] = key
+				}
+				continue
+			}
 			m.keys = append(m.keys, hash)
 			m.mapping[hash] = key
 		}
@@ -47,6 +165,11 @@ func (m *Map) Add(keys ...string) {
 	sort.Ints(m.keys)
 }
 
+// IsEmpty 判断哈希环上是否没有任何节点，nil的Map也视为空
+func (m *Map) IsEmpty() bool {
+	return m == nil || len(m.keys) == 0
+}
+
 // Get 根据key选择节点
 // 返回哈希环上顺时针方向最近的节点
 func (m *Map) Get(key string) string {
@@ -54,7 +177,51 @@ func (m *Map) Get(key string) string {
 		return ""
 	}
 
-	hash := int(m.hash([]byte(key)))
+	return m.ownerOf(m.hashKey(key))
+}
+
+// KeyHash 返回key在哈希环上的位置，与Get使用相同的哈希函数和盐值
+func (m *Map) KeyHash(key string) uint32 {
+	return uint32(m.hashKey(key))
+}
+
+// HashRange 是哈希环上的一段范围 (Start, End]，Start 不小于 End 时跨越环的起点，
+// Start 等于 End 表示整个哈希环
+type HashRange struct {
+	Start uint32 // 范围的起点（不包含）
+	End   uint32 // 范围的终点（包含）
+}
+
+// Contains 判断哈希值是否落在范围内
+func (r HashRange) Contains(hash uint32) bool {
+	if r.Start < r.End {
+		return r.Start < hash && hash <= r.End
+	}
+	return hash > r.Start || hash <= r.End
+}
+
+// Owned 返回哈希环上归属node的所有范围，按哈希值从小到大排列，相邻的范围会合并
+func (m *Map) Owned(node string) []HashRange {
+	var ranges []HashRange
+	for i, end := range m.keys {
+		if m.mapping[end] != node {
+			continue
+		}
+		start := m.keys[(i+len(m.keys)-1)%len(m.keys)]
+		if n := len(ranges); n > 0 && int(ranges[n-1].End) == start {
+			ranges[n-1].End = uint32(end)
+			continue
+		}
+		ranges = append(ranges, HashRange{uint32(start), uint32(end)})
+	}
+	return ranges
+}
+
+// ownerOf 返回哈希值hash在哈希环上的归属节点，哈希环为空时返回空字符串
+func (m *Map) ownerOf(hash int) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
 	// 二分查找，找到第一个大于等于hash的节点
 	index := sort.Search(len(m.keys), func(i int) bool {
 		return m.keys[i] >= hash
@@ -62,3 +229,84 @@ func (m *Map) Get(key string) string {
 	// 如果没找到，或者找到的位置超出切片范围，则环绕到第一个节点
 	return m.mapping[m.keys[index%len(m.keys)]]
 }
+
+// GetN 返回哈希环上从key的位置开始顺时针方向的前n个不同节点
+// 第一个即Get返回的归属节点，其余节点可作为故障转移的备选
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+
+	hash := m.hashKey(key)
+	index := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.mapping[m.keys[(index+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Nodes 返回哈希环上的所有真实节点，按添加顺序排列
+func (m *Map) Nodes() []string {
+	return append([]string(nil), m.nodes...)
+}
+
+// ringState 是哈希环导出时的序列化格式
+type ringState struct {
+	Hash     string   `json:"hash"`           // 哈希函数的注册名
+	Replicas int      `json:"replicas"`       // 虚拟节点倍数
+	Nodes    []string `json:"nodes"`          // 真实节点，按添加顺序排列
+	Salt     []byte   `json:"salt,omitempty"` // 哈希盐值，未设置时省略
+
+	// VNodeFormat 虚拟节点名称的格式，早期版本导出的数据没有该字段，它们使用LegacyVNodeFormat
+	VNodeFormat string `json:"vnode_format,omitempty"`
+}
+
+// Export 将哈希环序列化为字节切片
+// 导出内容包括节点集合、虚拟节点倍数、虚拟节点名称的格式以及哈希函数的注册名，
+// 客户端可以通过ImportMap还原出相同的哈希环，从而在本地计算key的归属节点
+func (m *Map) Export() []byte {
+	data, _ := json.Marshal(ringState{
+		Hash:     m.hashName,
+		Replicas: m.nreplicas,
+		Nodes:    m.nodes,
+		Salt:     m.salt,
+
+		VNodeFormat: m.vnodeFmt,
+	})
+	return data
+}
+
+// ImportMap 从Export导出的数据还原哈希环
+// 如果导出方使用的是匿名哈希函数，或者哈希函数未在本进程注册，则返回错误，
+// 避免两端使用不同的哈希函数导致对key的归属产生分歧
+func ImportMap(data []byte) (*Map, error) {
+	var st ringState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("consistenthash: decoding ring: %v", err)
+	}
+	if st.Hash == "" {
+		return nil, fmt.Errorf("consistenthash: ring was exported with an unnamed hash function")
+	}
+	if st.Replicas <= 0 {
+		return nil, fmt.Errorf("consistenthash: invalid replica count %d", st.Replicas)
+	}
+	m, err := NewMapByName(st.Replicas, st.Hash)
+	if err != nil {
+		return nil, err
+	}
+	m.salt = st.Salt
+	if st.VNodeFormat == "" {
+		st.VNodeFormat = LegacyVNodeFormat
+	}
+	m.vnodeFmt = st.VNodeFormat
+	m.Add(st.Nodes...)
+	return m, nil
+}
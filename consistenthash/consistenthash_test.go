@@ -2,7 +2,10 @@
 package consistenthash
 
 import (
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -16,6 +19,7 @@ func TestHashing(t *testing.T) {
 		i, _ := strconv.Atoi(string(key))
 		return uint32(i)
 	})
+	hash.SetVNodeFormat(LegacyVNodeFormat) // 虚拟节点名称为纯数字，便于预测哈希值
 
 	// 添加3个节点："6", "4", "2"
 	// 由于虚拟节点倍数为3，所以每个节点会产生3个虚拟节点
@@ -52,3 +56,288 @@ func TestHashing(t *testing.T) {
 		}
 	}
 }
+
+// TestExportImport 测试哈希环导出后再导入，key的归属保持一致
+func TestExportImport(t *testing.T) {
+	m := NewMap(50, nil)
+	m.Add("http://a:8001", "http://b:8002", "http://c:8003")
+
+	imported, err := ImportMap(m.Export())
+	if err != nil {
+		t.Fatalf("导入哈希环失败: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if got, want := imported.Get(key), m.Get(key); got != want {
+			t.Fatalf("键 %s 归属不一致: 期望 %s, 得到 %s", key, want, got)
+		}
+	}
+}
+
+// TestImportMismatchedHash 测试哈希函数不匹配时导入报错
+func TestImportMismatchedHash(t *testing.T) {
+	// 匿名的自定义哈希函数无法在其他进程还原
+	custom := NewMap(3, func(key []byte) uint32 { return 0 })
+	custom.Add("a")
+	if _, err := ImportMap(custom.Export()); err == nil {
+		t.Fatal("期望匿名哈希函数导入失败，但未返回错误")
+	}
+
+	// 未注册的哈希函数名
+	data := []byte(`{"hash":"not-registered","replicas":3,"nodes":["a"]}`)
+	if _, err := ImportMap(data); err == nil || !strings.Contains(err.Error(), "not-registered") {
+		t.Fatalf("期望返回未知哈希函数的错误，得到 %v", err)
+	}
+
+	// 已注册的具名哈希函数可以正常导入
+	RegisterHash("zero", func(key []byte) uint32 { return 0 })
+	named, err := NewMapByName(3, "zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named.Add("a")
+	if _, err := ImportMap(named.Export()); err != nil {
+		t.Fatalf("具名哈希函数导入失败: %v", err)
+	}
+}
+
+// TestSalt 测试盐值对key归属的影响
+func TestSalt(t *testing.T) {
+	nodes := []string{"node-a", "node-b", "node-c", "node-d"}
+	build := func(salt []byte) *Map {
+		m := NewSaltedMap(50, nil, salt)
+		m.Add(nodes...)
+		return m
+	}
+
+	m1, m2 := build([]byte("salt-1")), build([]byte("salt-1"))
+	m3 := build([]byte("salt-2"))
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if m1.Get(key) != m2.Get(key) {
+			t.Fatalf("相同盐值下键 %s 的归属不一致", key)
+		}
+		if m1.Get(key) != m3.Get(key) {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("不同盐值下所有键的归属都相同")
+	}
+
+	// 盐值随导出一起传递
+	imported, err := ImportMap(m3.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		if imported.Get(key) != m3.Get(key) {
+			t.Fatalf("导入后键 %s 的归属不一致", key)
+		}
+	}
+}
+
+// TestGetN 测试按顺时针方向返回多个不同节点
+func TestGetN(t *testing.T) {
+	hash := NewMap(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.SetVNodeFormat(LegacyVNodeFormat) // 虚拟节点名称为纯数字，便于预测哈希值
+	// 虚拟节点哈希值：2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	if got := hash.GetN("11", 2); len(got) != 2 || got[0] != "2" || got[1] != "4" {
+		t.Errorf("GetN(11, 2) 应返回 [2 4], 得到 %v", got)
+	}
+	if got := hash.GetN("27", 5); len(got) != 3 || got[0] != "2" {
+		t.Errorf("GetN(27, 5) 应返回全部3个节点且以2开头, 得到 %v", got)
+	}
+}
+
+// TestCollisionTieBreak 测试哈希冲突时归属与节点添加顺序无关
+func TestCollisionTieBreak(t *testing.T) {
+	// 所有虚拟节点的哈希值都相同
+	constant := func(key []byte) uint32 { return 42 }
+
+	m1 := NewMap(3, constant)
+	m1.Add("node-b", "node-a", "node-c")
+	m2 := NewMap(3, constant)
+	m2.Add("node-c", "node-a")
+	m2.Add("node-b")
+
+	for _, key := range []string{"x", "y", "z"} {
+		if got1, got2 := m1.Get(key), m2.Get(key); got1 != "node-a" || got2 != "node-a" {
+			t.Errorf("哈希冲突时应选择node-a, 得到 %s 和 %s", got1, got2)
+		}
+	}
+}
+
+// TestXXHash 测试XXHash与xxHash64参考实现的结果一致
+func TestXXHash(t *testing.T) {
+	vectors := map[string]uint64{
+		"":    0xef46db3751d8e999,
+		"a":   0xd24ec4f1a98c6e5b,
+		"abc": 0x44bc2cf5ad770999,
+		"Nobody inspects the spammish repetition": 0xfbcea83c8a378bf1,
+	}
+	for s, want := range vectors {
+		if got := xxhash64([]byte(s)); got != want {
+			t.Errorf("xxhash64(%q) = %x, 期望 %x", s, got, want)
+		}
+	}
+}
+
+// TestDistribution 比较crc32与xxhash在哈希环上的分布均匀程度
+func TestDistribution(t *testing.T) {
+	// spread 返回各节点负载的变异系数（标准差/均值）和最多/最少负载比，越小越均匀
+	spread := func(hashName string) (cv, ratio float64) {
+		m, err := NewMapByName(50, hashName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const nodes, keys = 10, 100000
+		for i := 0; i < nodes; i++ {
+			m.Add("http://10.0.0." + strconv.Itoa(i) + ":8001")
+		}
+		counts := make(map[string]int)
+		for i := 0; i < keys; i++ {
+			counts[m.Get("user:"+strconv.Itoa(i))]++
+		}
+		mean := float64(keys) / nodes
+		lo, hi := keys, 0
+		var sq float64
+		for _, n := range counts {
+			lo, hi = min(lo, n), max(hi, n)
+			sq += (float64(n) - mean) * (float64(n) - mean)
+		}
+		return math.Sqrt(sq/nodes) / mean, float64(hi) / float64(lo)
+	}
+
+	crcCV, crcRatio := spread(HashCRC32)
+	xxCV, xxRatio := spread(HashXXHash)
+	t.Logf("变异系数: crc32=%.3f xxhash=%.3f；最多/最少节点负载比: crc32=%.2f xxhash=%.2f",
+		crcCV, xxCV, crcRatio, xxRatio)
+	if xxCV >= crcCV || xxRatio >= crcRatio {
+		t.Errorf("xxhash的分布应比crc32更均匀: cv %.3f/%.3f, ratio %.2f/%.2f", xxCV, crcCV, xxRatio, crcRatio)
+	}
+	if xxRatio > 2 {
+		t.Errorf("xxhash分布过于不均: %.2f", xxRatio)
+	}
+}
+
+// TestIsEmpty 测试空哈希环的判断
+func TestIsEmpty(t *testing.T) {
+	var nilMap *Map
+	m := NewMap(3, nil)
+	if !nilMap.IsEmpty() || !m.IsEmpty() {
+		t.Fatal("nil或未添加节点的Map应为空")
+	}
+	m.Add("a")
+	if m.IsEmpty() {
+		t.Fatal("添加节点后Map不应为空")
+	}
+}
+
+// TestVNodeFormat 测试相似节点名的虚拟节点不会重名
+func TestVNodeFormat(t *testing.T) {
+	const replicas = 20
+	build := func(format string) *Map {
+		m := NewMap(replicas, nil)
+		m.SetVNodeFormat(format)
+		m.Add("1", "11")
+		return m
+	}
+
+	// 旧格式下节点"1"的第11个虚拟节点与节点"11"的第1个都是"111"，哈希环上位置重合
+	if n := len(build(LegacyVNodeFormat).keys); n >= 2*replicas {
+		t.Fatalf("期望旧格式产生重名的虚拟节点, 得到 %d 个不同位置", n)
+	}
+	// 默认格式使用分隔符，两个节点的虚拟节点互不相同
+	if n := len(build("").keys); n != 2*replicas {
+		t.Fatalf("期望 %d 个不同的虚拟节点, 得到 %d", 2*replicas, n)
+	}
+}
+
+// TestImportLegacyVNodeFormat 测试早期版本导出的哈希环按旧格式还原
+func TestImportLegacyVNodeFormat(t *testing.T) {
+	legacy := NewMap(50, nil)
+	legacy.SetVNodeFormat(LegacyVNodeFormat)
+	legacy.Add("http://a:8001", "http://b:8002", "http://c:8003")
+
+	// 早期版本导出的数据没有vnode_format字段
+	data := []byte(`{"hash":"crc32","replicas":50,"nodes":["http://a:8001","http://b:8002","http://c:8003"]}`)
+	imported, err := ImportMap(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		key := "key" + strconv.Itoa(i)
+		if got, want := imported.Get(key), legacy.Get(key); got != want {
+			t.Fatalf("键 %s 归属不一致: 期望 %s, 得到 %s", key, want, got)
+		}
+	}
+}
+
+// TestDiff 测试两个哈希环之间归属变化的范围
+func TestDiff(t *testing.T) {
+	atoi := func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	}
+	build := func(nodes ...string) *Map {
+		m := NewMap(3, atoi)
+		m.SetVNodeFormat(LegacyVNodeFormat)
+		m.Add(nodes...)
+		return m
+	}
+	// 旧哈希环的虚拟节点为 2, 4, 6, 12, 14, 16, 22, 24, 26，新增节点"8"带来 8, 18, 28
+	old, cur := build("6", "4", "2"), build("6", "4", "2", "8")
+	delta := cur.Diff(old)
+	want := []RangeMove{
+		{HashRange{6, 8}, "2", "8"},
+		{HashRange{16, 18}, "2", "8"},
+		{HashRange{26, 28}, "2", "8"},
+	}
+	if !reflect.DeepEqual(delta.Moves, want) {
+		t.Fatalf("Moves = %+v, want %+v", delta.Moves, want)
+	}
+	if lost := delta.Lost("2"); len(lost) != 3 {
+		t.Fatalf("Lost(2) = %+v, want 3 ranges", lost)
+	}
+	if lost := delta.Lost("4"); len(lost) != 0 {
+		t.Fatalf("Lost(4) = %+v, want none", lost)
+	}
+	if mv, ok := delta.Moved("27"); !ok || mv.From != "2" || mv.To != "8" {
+		t.Fatalf("Moved(27) = %+v, %v", mv, ok)
+	}
+	if _, ok := delta.Moved("29"); ok {
+		t.Fatal("Moved(29) should be false, key still wraps to node 2")
+	}
+	if back := old.Diff(cur); len(back.Lost("8")) != 3 {
+		t.Fatalf("reverse diff Lost(8) = %+v", back.Lost("8"))
+	}
+
+	// 使用真实哈希函数时，Moved与新旧哈希环上的查询结果一致
+	m1, m2 := NewMap(50, nil), NewMap(50, nil)
+	m1.Add("a", "b", "c")
+	m2.Add("a", "c", "d")
+	delta = m2.Diff(m1)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		from, to := m1.Get(key), m2.Get(key)
+		mv, ok := delta.Moved(key)
+		if ok != (from != to) || ok && (mv.From != from || mv.To != to) {
+			t.Fatalf("Moved(%q) = %+v, %v; Get old=%q new=%q", key, mv, ok, from, to)
+		}
+	}
+
+	// 旧哈希环为空时整个环都发生变化
+	empty := cur.Diff(NewMap(3, atoi))
+	if _, ok := empty.Moved("100"); !ok {
+		t.Fatal("diff from an empty ring should cover every key")
+	}
+}
@@ -0,0 +1,79 @@
+package consistenthash
+
+import "sort"
+
+// RangeMove 描述哈希环上一段哈希值的归属变化
+type RangeMove struct {
+	HashRange
+	From string // 旧的归属节点，旧哈希环为空时为空字符串
+	To   string // 新的归属节点，新哈希环为空时为空字符串
+}
+
+// RingDelta 是两个哈希环之间归属发生变化的所有哈希范围，按哈希值从小到大排列
+type RingDelta struct {
+	Moves []RangeMove
+	ring  *Map // 新哈希环，用于计算key的哈希值
+}
+
+// Diff 计算从旧哈希环old到m的归属变化
+// 两个哈希环必须使用相同的哈希函数和盐值，否则结果没有意义。
+// 节点变化后，各节点可以据此找出自己不再拥有的key（例如作为本地淘汰的候选），
+// 而不必对每个本地key分别在新旧哈希环上查询。old为nil时视为空哈希环
+func (m *Map) Diff(old *Map) RingDelta {
+	if old == nil {
+		old = &Map{}
+	}
+	delta := RingDelta{ring: m}
+	// 两个哈希环所有虚拟节点位置的并集把环切分为若干段，每段在两个哈希环上各自只有一个归属节点
+	points := make([]int, 0, len(m.keys)+len(old.keys))
+	points = append(append(points, m.keys...), old.keys...)
+	sort.Ints(points)
+	uniq := points[:0]
+	for i, p := range points {
+		if i == 0 || p != points[i-1] {
+			uniq = append(uniq, p)
+		}
+	}
+	for i, end := range uniq {
+		start := uniq[(i+len(uniq)-1)%len(uniq)]
+		from, to := old.ownerOf(end), m.ownerOf(end)
+		if from == to {
+			continue
+		}
+		// 与上一段相邻且变化相同时合并
+		if n := len(delta.Moves); n > 0 {
+			last := &delta.Moves[n-1]
+			if int(last.End) == start && last.From == from && last.To == to {
+				last.End = uint32(end)
+				continue
+			}
+		}
+		delta.Moves = append(delta.Moves, RangeMove{HashRange{uint32(start), uint32(end)}, from, to})
+	}
+	return delta
+}
+
+// Lost 返回node失去归属的所有范围
+func (d RingDelta) Lost(node string) []RangeMove {
+	var lost []RangeMove
+	for _, mv := range d.Moves {
+		if mv.From == node {
+			lost = append(lost, mv)
+		}
+	}
+	return lost
+}
+
+// Moved 返回key所在的发生变化的范围，key的归属没有变化时返回false
+func (d RingDelta) Moved(key string) (RangeMove, bool) {
+	if d.ring == nil {
+		return RangeMove{}, false
+	}
+	hash := d.ring.KeyHash(key)
+	for _, mv := range d.Moves {
+		if mv.Contains(hash) {
+			return mv, true
+		}
+	}
+	return RangeMove{}, false
+}
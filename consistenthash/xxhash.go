@@ -0,0 +1,87 @@
+package consistenthash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// xxHash64 算法常量
+const (
+	prime64x1 uint64 = 11400714785074694791
+	prime64x2 uint64 = 14029467366897019727
+	prime64x3 uint64 = 1609587929392839161
+	prime64x4 uint64 = 9650029242287828579
+	prime64x5 uint64 = 2870177450012600261
+)
+
+// XXHash 使用xxHash64（种子为0）计算哈希值，并将64位结果折叠为32位
+// 哈希环上的位置是32位的，折叠后冲突概率与其他32位哈希相同，不会因为使用64位算法而减少；
+// 相比crc32的收益在于分布更均匀（各节点负载更接近）。
+// 注意：改用XXHash会改变所有key的归属，与使用crc32的节点不兼容，集群内所有节点和客户端必须同时切换
+func XXHash(data []byte) uint32 {
+	h := xxhash64(data)
+	return uint32(h ^ h>>32)
+}
+
+// xxhash64 计算data的xxHash64值，种子为0
+func xxhash64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+
+	if n >= 32 {
+		p1, p2 := prime64x1, prime64x2 // 使用变量使加法和取负按uint64回绕
+		v1 := p1 + p2
+		v2 := p2
+		v3 := uint64(0)
+		v4 := -p1
+		for len(b) >= 32 {
+			v1 = xxround(v1, binary.LittleEndian.Uint64(b[0:8]))
+			v2 = xxround(v2, binary.LittleEndian.Uint64(b[8:16]))
+			v3 = xxround(v3, binary.LittleEndian.Uint64(b[16:24]))
+			v4 = xxround(v4, binary.LittleEndian.Uint64(b[24:32]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxmerge(h, v1)
+		h = xxmerge(h, v2)
+		h = xxmerge(h, v3)
+		h = xxmerge(h, v4)
+	} else {
+		h = prime64x5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxround(0, binary.LittleEndian.Uint64(b[:8]))
+		h = bits.RotateLeft64(h, 27)*prime64x1 + prime64x4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b[:4])) * prime64x1
+		h = bits.RotateLeft64(h, 23)*prime64x2 + prime64x3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * prime64x5
+		h = bits.RotateLeft64(h, 11) * prime64x1
+	}
+
+	h ^= h >> 33
+	h *= prime64x2
+	h ^= h >> 29
+	h *= prime64x3
+	h ^= h >> 32
+	return h
+}
+
+func xxround(acc, input uint64) uint64 {
+	acc += input * prime64x2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64x1
+}
+
+func xxmerge(acc, val uint64) uint64 {
+	val = xxround(0, val)
+	acc ^= val
+	return acc*prime64x1 + prime64x4
+}
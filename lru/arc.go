@@ -2,6 +2,7 @@ package lru
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -22,14 +23,36 @@ type ARC struct {
 	b2 *list.List
 	// 缓存数据
 	cache map[string]*list.Element
+	// B1、B2 中的历史记录，用于在写入时判断是否命中历史记录
+	ghosts map[string]*list.Element
 	// 当前大小
 	size int
 	// 自适应参数 p
 	p int
+	// 为 true 时历史记录命中不再调整 p
+	pFrozen bool
+	// Get 的命中和未命中次数
+	hits, misses uint64
 	// 停止清理的通道
 	stopCh chan struct{}
+	// 共享清理器，不为nil时不启动独立的清理协程
+	janitor *Janitor
+	// 更新已存在的键时是否将其从 T1 提升到 T2
+	promoteOnWrite bool
+	// 可选，条目过期时调用，可以否决删除
+	onExpire ExpireFunc
+
+	// OnEvicted 可选，真实条目离开缓存时调用：容量不足被移入历史记录（值随之丢弃）、
+	// 过期被删除、Remove 和 Clear。覆盖已存在的键、历史记录被裁剪或过期时不调用，
+	// 历史记录只保存键，对应的值在进入历史记录时已经通知过。
+	// 需要在使用缓存之前设置；回调在持有缓存锁时执行，不能再调用该缓存的方法
+	OnEvicted func(key string, value interface{})
 }
 
+// ExpireFunc 在条目过期时调用，返回 keep=true 时保留条目并以 newTTL 重新设置过期时间，
+// newTTL 不大于 0 表示不再过期。回调在持有缓存锁时执行，不能再调用该缓存的方法
+type ExpireFunc func(key string, value any) (keep bool, newTTL time.Duration)
+
 // arcEntry 表示缓存条目
 type arcEntry struct {
 	key   string
@@ -42,19 +65,90 @@ type arcEntry struct {
 
 // NewARC 创建一个新的 ARC 缓存
 func NewARC(capacity int) *ARC {
-	arc := &ARC{
+	arc := newARC(capacity)
+	// 启动清理协程
+	go arc.cleanupLoop()
+	return arc
+}
+
+// NewARCWithJanitor 创建一个由共享清理器负责过期清理的 ARC 缓存
+// 缓存不会启动自己的清理协程，Close 时自动从清理器注销
+func NewARCWithJanitor(capacity int, j *Janitor) *ARC {
+	arc := newARC(capacity)
+	arc.janitor = j
+	j.Register(arc)
+	return arc
+}
+
+// newARC 初始化 ARC 的内部结构
+func newARC(capacity int) *ARC {
+	return &ARC{
 		capacity: capacity,
 		t1:       list.New(),
 		t2:       list.New(),
 		b1:       list.New(),
 		b2:       list.New(),
 		cache:    make(map[string]*list.Element),
+		ghosts:   make(map[string]*list.Element),
 		p:        0,
 		stopCh:   make(chan struct{}),
+
+		promoteOnWrite: true,
 	}
-	// 启动清理协程
-	go arc.cleanupLoop()
-	return arc
+}
+
+// SetPromoteOnWrite 设置更新已存在的键时是否将其视为一次访问并提升到 T2，默认开启
+// 关闭后只有 Get 会把条目从 T1 提升到 T2，写入只刷新条目在所在列表中的位置，
+// 这样频繁写入但从不读取的键不会被当作热点，自适应参数 p 只反映读取模式，与 ARC 论文一致
+func (arc *ARC) SetPromoteOnWrite(enabled bool) {
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+	arc.promoteOnWrite = enabled
+}
+
+// SetOnExpire 设置过期回调，在清理过期条目和读取到过期条目时调用
+// 可用于按条件续期，例如关联资源仍然活跃时保留会话，而无需外部的刷新循环
+func (arc *ARC) SetOnExpire(fn ExpireFunc) {
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+	arc.onExpire = fn
+}
+
+// expired 判断条目是否已过期且应被删除，过期回调否决删除时按返回的 newTTL 续期
+func (arc *ARC) expired(entry *arcEntry, now time.Time) bool {
+	if entry.expireAt.IsZero() || !now.After(entry.expireAt) {
+		return false
+	}
+	if arc.onExpire != nil {
+		if keep, newTTL := arc.onExpire(entry.key, entry.value); keep {
+			if newTTL > 0 {
+				entry.expireAt = now.Add(newTTL)
+			} else {
+				entry.expireAt = time.Time{}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// evicted 在真实条目离开缓存时调用 OnEvicted
+func (arc *ARC) evicted(entry *arcEntry) {
+	if arc.OnEvicted != nil {
+		arc.OnEvicted(entry.key, entry.value)
+	}
+}
+
+// promote 将条目从 T1 提升到 T2，已在 T2 中时移到 T2 的前面
+func (arc *ARC) promote(ele *list.Element) {
+	entry := ele.Value.(*arcEntry)
+	if entry.inT2 {
+		arc.t2.MoveToFront(ele)
+		return
+	}
+	arc.t1.Remove(ele)
+	entry.inT2 = true
+	arc.cache[entry.key] = arc.t2.PushFront(entry)
 }
 
 // cleanupLoop 定期清理过期条目
@@ -72,21 +166,25 @@ func (arc *ARC) cleanupLoop() {
 	}
 }
 
-// cleanup 清理所有列表中的过期条目
-func (arc *ARC) cleanup() {
-	// 清理 T1
-	arc.cleanupList(arc.t1)
-	// 清理 T2
-	arc.cleanupList(arc.t2)
-	// 清理 B1
-	arc.cleanupList(arc.b1)
-	// 清理 B2
-	arc.cleanupList(arc.b2)
+// cleanup 清理所有列表中的过期条目，返回被清理的缓存条目数量
+func (arc *ARC) cleanup() int {
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+
+	// 清理 T1、T2 中的缓存条目
+	n := arc.cleanupList(arc.t1, true)
+	n += arc.cleanupList(arc.t2, true)
+	// 清理 B1、B2 中的历史记录，它们已不在缓存中，不影响缓存大小
+	arc.cleanupList(arc.b1, false)
+	arc.cleanupList(arc.b2, false)
+	return n
 }
 
-// cleanupList 清理指定列表中的过期条目
-func (arc *ARC) cleanupList(l *list.List) {
+// cleanupList 清理指定列表中的过期条目，返回清理的数量
+// live 表示列表中是否为缓存中的真实条目，历史记录列表只需从列表中移除
+func (arc *ARC) cleanupList(l *list.List, live bool) int {
 	now := time.Now()
+	n := 0
 	for e := l.Front(); e != nil; {
 		next := e.Next()
 		entry, ok := e.Value.(*arcEntry)
@@ -95,13 +193,33 @@ func (arc *ARC) cleanupList(l *list.List) {
 			e = next
 			continue
 		}
-		if !entry.expireAt.IsZero() && now.After(entry.expireAt) {
+		var drop bool
+		if live {
+			drop = arc.expired(entry, now)
+		} else {
+			// 历史记录不在缓存中，过期时直接移除而不询问过期回调
+			drop = !entry.expireAt.IsZero() && now.After(entry.expireAt)
+		}
+		if drop {
 			l.Remove(e)
-			delete(arc.cache, entry.key)
-			arc.size--
+			if live {
+				delete(arc.cache, entry.key)
+				arc.size--
+				arc.evicted(entry)
+			} else {
+				delete(arc.ghosts, entry.key)
+			}
+			n++
 		}
 		e = next
 	}
+	return n
+}
+
+// EvictExpired 立即同步清理所有过期条目，返回被清理的缓存条目数量
+// 主要用于测试：不必等待后台清理协程，即可确定性地断言过期结果
+func (arc *ARC) EvictExpired() int {
+	return arc.cleanup()
 }
 
 // Put 添加或更新缓存值
@@ -130,15 +248,13 @@ func (arc *ARC) PutWithTTL(key string, value interface{}, ttl time.Duration) {
 		} else {
 			entry.expireAt = time.Time{}
 		}
-		// 如果元素在 T1 中
-		if !entry.inT2 {
-			// 从 T1 移动到 T2
-			arc.t1.Remove(ele)
-			entry.inT2 = true
-			arc.t2.PushFront(ele)
-		} else {
-			// 如果元素在 T2 中，移动到 T2 的前面
+		switch {
+		case arc.promoteOnWrite:
+			arc.promote(ele)
+		case entry.inT2:
 			arc.t2.MoveToFront(ele)
+		default:
+			arc.t1.MoveToFront(ele)
 		}
 		return
 	}
@@ -153,16 +269,44 @@ func (arc *ARC) PutWithTTL(key string, value interface{}, ttl time.Duration) {
 		ent.expireAt = time.Now().Add(ttl)
 	}
 
+	// 命中历史记录时调整 p 并把条目直接放入 T2：
+	// 命中 B1 说明 T1 太小，增大 p；命中 B2 说明 T2 太小，减小 p
+	hitB2 := false
+	if ghost, ok := arc.ghosts[key]; ok {
+		hitB2 = ghost.Value.(*arcEntry).inT2
+		switch {
+		case hitB2 && !arc.pFrozen:
+			arc.p = max(0, arc.p-max(1, arc.b1.Len()/arc.b2.Len()))
+		case !hitB2 && !arc.pFrozen:
+			arc.p = min(arc.capacity, arc.p+max(1, arc.b2.Len()/arc.b1.Len()))
+		}
+		if hitB2 {
+			arc.b2.Remove(ghost)
+		} else {
+			arc.b1.Remove(ghost)
+		}
+		delete(arc.ghosts, key)
+		ent.inT2 = true
+	}
+
 	// 如果缓存未满
 	if arc.size < arc.capacity {
-		ele := arc.t1.PushFront(ent)
-		arc.cache[key] = ele
+		arc.insert(ent)
 		arc.size++
 		return
 	}
 
 	// 自适应替换
-	arc.replace(ent)
+	arc.replace(ent, hitB2)
+}
+
+// insert 将新条目加入 T1 或 T2 的前面
+func (arc *ARC) insert(ent *arcEntry) {
+	if ent.inT2 {
+		arc.cache[ent.key] = arc.t2.PushFront(ent)
+	} else {
+		arc.cache[ent.key] = arc.t1.PushFront(ent)
+	}
 }
 
 // Get 获取缓存值
@@ -173,7 +317,7 @@ func (arc *ARC) Get(key string) (interface{}, bool) {
 	if ele, ok := arc.cache[key]; ok {
 		entry := ele.Value.(*arcEntry)
 		// 检查是否过期
-		if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		if arc.expired(entry, time.Now()) {
 			// 如果过期，删除条目
 			if entry.inT2 {
 				arc.t2.Remove(ele)
@@ -182,98 +326,89 @@ func (arc *ARC) Get(key string) (interface{}, bool) {
 			}
 			delete(arc.cache, key)
 			arc.size--
+			arc.misses++
+			arc.evicted(entry)
 			return nil, false
 		}
 
-		// 如果元素在 T1 中
-		if !entry.inT2 {
-			// 从 T1 移动到 T2
-			arc.t1.Remove(ele)
-			entry.inT2 = true
-			arc.t2.PushFront(ele)
-		} else {
-			// 如果元素在 T2 中，移动到 T2 的前面
-			arc.t2.MoveToFront(ele)
-		}
+		// 从 T1 提升到 T2，或移到 T2 的前面
+		arc.promote(ele)
+		arc.hits++
 		return entry.value, true
 	}
+	arc.misses++
 	return nil, false
 }
 
-// Close 关闭缓存，停止清理协程
-func (arc *ARC) Close() {
-	close(arc.stopCh)
+// Peek 获取缓存值但不提升条目、不调整 p，也不计入命中统计，适用于诊断或统计接口
+// 已过期的条目视为不存在，但不会被删除，也不会调用过期回调
+func (arc *ARC) Peek(key string) (interface{}, bool) {
+	arc.mu.RLock()
+	defer arc.mu.RUnlock()
+
+	if ele, ok := arc.cache[key]; ok {
+		entry := ele.Value.(*arcEntry)
+		if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+			return nil, false
+		}
+		return entry.value, true
+	}
+	return nil, false
 }
 
-// replace 执行替换操作
-func (arc *ARC) replace(ent *arcEntry) {
-	// 如果 T1 和 T2 都为空，直接添加新条目
-	if arc.t1.Len() == 0 && arc.t2.Len() == 0 {
-		ele := arc.t1.PushFront(ent)
-		arc.cache[ent.key] = ele
+// Close 关闭缓存，停止清理协程；使用共享清理器时从清理器注销
+func (arc *ARC) Close() {
+	if arc.janitor != nil {
+		arc.janitor.Unregister(arc)
 		return
 	}
+	close(arc.stopCh)
+}
 
-	var last *list.Element
-	var lastEntry *arcEntry
-
-	// 如果 T1 不为空且 (p > 0 或 B2 为空)
-	if arc.t1.Len() > 0 && (arc.p > 0 || arc.b2.Len() == 0) {
-		last = arc.t1.Back()
-		if last == nil {
-			return
-		}
-
-		var ok bool
-		lastEntry, ok = last.Value.(*arcEntry)
-		if !ok {
-			return
-		}
-
-		arc.t1.Remove(last)
-		// 将元素移动到 B1，并限制 B1 的大小
-		arc.b1.PushFront(lastEntry)
-		lastEntry.inT2 = false
-		if arc.b1.Len() > arc.capacity {
-			if old := arc.b1.Back(); old != nil {
-				arc.b1.Remove(old)
-			}
-		}
-		// 减小 p
-		arc.p = max(0, arc.p-1)
-	} else {
-		last = arc.t2.Back()
-		if last == nil {
-			return
-		}
-
-		var ok bool
-		lastEntry, ok = last.Value.(*arcEntry)
-		if !ok {
-			return
-		}
+// replace 淘汰一个缓存条目并添加新条目
+// T1 的长度超过 p 时淘汰 T1 的末尾，否则淘汰 T2 的末尾；新条目命中 B2 且 T1 的长度恰好等于 p 时
+// 同样淘汰 T1，与 ARC 论文中的 REPLACE 一致。被淘汰的条目进入对应的历史记录列表
+func (arc *ARC) replace(ent *arcEntry, hitB2 bool) {
+	arc.evictOne(hitB2)
+	arc.insert(ent)
+}
 
-		arc.t2.Remove(last)
-		// 将元素移动到 B2，并限制 B2 的大小
-		arc.b2.PushFront(lastEntry)
-		lastEntry.inT2 = true
-		if arc.b2.Len() > arc.capacity {
-			if old := arc.b2.Back(); old != nil {
-				arc.b2.Remove(old)
-			}
-		}
-		// 增加 p
-		arc.p = min(arc.capacity, arc.p+1)
+// evictOne 按 REPLACE 的规则把 T1 或 T2 末尾的条目移入历史记录，不改变 size
+func (arc *ARC) evictOne(hitB2 bool) {
+	t1Len := arc.t1.Len()
+	if t1Len > 0 && (t1Len > arc.p || (hitB2 && t1Len == arc.p) || arc.t2.Len() == 0) {
+		arc.demote(arc.t1, arc.b1)
+	} else if arc.t2.Len() > 0 {
+		arc.demote(arc.t2, arc.b2)
 	}
+}
 
-	// 删除缓存中的旧条目
-	if lastEntry != nil {
-		delete(arc.cache, lastEntry.key)
+// RemoveOldest 按 REPLACE 的规则淘汰一个条目，被淘汰的键进入历史记录，缓存为空时不做任何操作
+func (arc *ARC) RemoveOldest() {
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+	if arc.size > 0 {
+		arc.evictOne(false)
+		arc.size--
 	}
+}
 
-	// 添加新条目到 T1
-	ele := arc.t1.PushFront(ent)
-	arc.cache[ent.key] = ele
+// demote 将 from 末尾的条目移入历史记录列表 to，并限制历史记录的长度
+// 历史记录只保留键，条目的值在这里丢弃，对调用方而言条目已离开缓存
+func (arc *ARC) demote(from, to *list.List) {
+	last := from.Back()
+	entry := last.Value.(*arcEntry)
+	from.Remove(last)
+	delete(arc.cache, entry.key)
+	arc.evicted(entry)
+	entry.value = nil
+
+	arc.ghosts[entry.key] = to.PushFront(entry)
+	if to.Len() > arc.capacity {
+		old := to.Back()
+		to.Remove(old)
+		delete(arc.ghosts, old.Value.(*arcEntry).key)
+	}
 }
 
 // Remove 删除缓存值
@@ -282,13 +417,15 @@ func (arc *ARC) Remove(key string) {
 	defer arc.mu.Unlock()
 
 	if ele, ok := arc.cache[key]; ok {
-		if ele.Value.(*arcEntry).inT2 {
+		entry := ele.Value.(*arcEntry)
+		if entry.inT2 {
 			arc.t2.Remove(ele)
 		} else {
 			arc.t1.Remove(ele)
 		}
 		delete(arc.cache, key)
 		arc.size--
+		arc.evicted(entry)
 	}
 }
 
@@ -297,11 +434,19 @@ func (arc *ARC) Clear() {
 	arc.mu.Lock()
 	defer arc.mu.Unlock()
 
+	if arc.OnEvicted != nil {
+		for _, l := range []*list.List{arc.t1, arc.t2} {
+			for e := l.Front(); e != nil; e = e.Next() {
+				arc.evicted(e.Value.(*arcEntry))
+			}
+		}
+	}
 	arc.t1.Init()
 	arc.t2.Init()
 	arc.b1.Init()
 	arc.b2.Init()
 	arc.cache = make(map[string]*list.Element)
+	arc.ghosts = make(map[string]*list.Element)
 	arc.size = 0
 	arc.p = 0
 }
@@ -313,6 +458,79 @@ func (arc *ARC) Size() int {
 	return arc.size
 }
 
+// ARCState 是 ARC 内部状态的只读快照
+type ARCState struct {
+	P  int // 自适应参数 p，即 T1 的目标长度
+	T1 int // T1 的长度
+	T2 int // T2 的长度
+	B1 int // B1 历史记录的长度
+	B2 int // B2 历史记录的长度
+}
+
+// State 返回 ARC 当前的内部状态，用于测试和诊断自适应替换的行为
+func (arc *ARC) State() ARCState {
+	arc.mu.RLock()
+	defer arc.mu.RUnlock()
+	return arc.stateLocked()
+}
+
+// stateLocked 返回内部状态，调用方需持有锁
+func (arc *ARC) stateLocked() ARCState {
+	return ARCState{P: arc.p, T1: arc.t1.Len(), T2: arc.t2.Len(), B1: arc.b1.Len(), B2: arc.b2.Len()}
+}
+
+// ARCStats 是 ARC 的统计快照
+type ARCStats struct {
+	Hits   uint64 // Get 命中次数
+	Misses uint64 // Get 未命中次数，包括读取到已过期的条目
+	ARCState
+}
+
+// Stats 返回命中统计和各列表的长度以及当前的 p，可以据此判断自适应替换是否在起作用
+func (arc *ARC) Stats() ARCStats {
+	arc.mu.RLock()
+	defer arc.mu.RUnlock()
+	return ARCStats{
+		Hits:     arc.hits,
+		Misses:   arc.misses,
+		ARCState: arc.stateLocked(),
+	}
+}
+
+// Keys 返回缓存中的所有键，先 T1 后 T2，各自从前到后（从最近使用到最久未使用）排列
+// 已过期但尚未清理的条目也会包含在内
+func (arc *ARC) Keys() []string {
+	arc.mu.RLock()
+	defer arc.mu.RUnlock()
+	keys := make([]string, 0, arc.t1.Len()+arc.t2.Len())
+	for _, l := range []*list.List{arc.t1, arc.t2} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			keys = append(keys, e.Value.(*arcEntry).key)
+		}
+	}
+	return keys
+}
+
+// SetP 将自适应参数 p 设置为指定值，p 超出 [0, capacity] 时返回错误且不生效
+// 可用于测试中确定性地断言下一次替换会淘汰哪个条目；未调用 FreezeP 时之后的历史记录命中仍会继续调整 p
+func (arc *ARC) SetP(p int) error {
+	if p < 0 || p > arc.capacity {
+		return fmt.Errorf("arc: p %d out of range [0, %d]", p, arc.capacity)
+	}
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+	arc.p = p
+	return nil
+}
+
+// FreezeP 冻结或恢复自适应参数 p，冻结后历史记录命中不再调整 p
+// 配合 SetP 可以让 ARC 退化为 T1、T2 按固定比例划分的缓存，用于与自适应的默认行为对比命中率
+func (arc *ARC) FreezeP(frozen bool) {
+	arc.mu.Lock()
+	defer arc.mu.Unlock()
+	arc.pFrozen = frozen
+}
+
 // Capacity 返回缓存容量
 func (arc *ARC) Capacity() int {
 	return arc.capacity
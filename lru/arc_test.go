@@ -30,6 +30,7 @@ package lru
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -289,8 +290,14 @@ func TestARCTTLConcurrent(t *testing.T) {
 		<-done
 	}
 
-	// 等待清理协程执行
-	time.Sleep(2 * time.Second)
+	// 等待 TTL 到期后同步清理，不依赖后台清理协程的执行时机
+	time.Sleep(150 * time.Millisecond)
+	if n := arc.EvictExpired(); n != 100 {
+		t.Errorf("EvictExpired should evict 100 entries, got %d", n)
+	}
+	if arc.Size() != 0 {
+		t.Errorf("Size should be 0 after EvictExpired, got %d", arc.Size())
+	}
 
 	// 检查是否都已过期
 	for i := 0; i < 100; i++ {
@@ -328,3 +335,271 @@ func TestARCTTLWithReplace(t *testing.T) {
 		t.Errorf("Get key3 failed, got %v, want value3", v)
 	}
 }
+
+func TestARCJanitor(t *testing.T) {
+	j := NewJanitor(10 * time.Millisecond)
+	defer j.Stop()
+
+	arc1 := NewARCWithJanitor(3, j)
+	arc2 := NewARCWithJanitor(3, j)
+	if j.Len() != 2 {
+		t.Fatalf("Janitor should track 2 caches, got %d", j.Len())
+	}
+
+	// 共享清理器负责清理过期条目
+	arc1.PutWithTTL("key1", "value1", 20*time.Millisecond)
+	arc2.PutWithTTL("key2", "value2", 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if arc1.Size() != 0 || arc2.Size() != 0 {
+		t.Errorf("expired entries should be swept, sizes %d and %d", arc1.Size(), arc2.Size())
+	}
+
+	// 关闭缓存后从清理器注销
+	arc1.Close()
+	if j.Len() != 1 {
+		t.Errorf("closed cache should be unregistered, got %d", j.Len())
+	}
+	arc2.Close()
+	if j.Len() != 0 {
+		t.Errorf("all caches should be unregistered, got %d", j.Len())
+	}
+}
+
+func TestARCPromoteOnWrite(t *testing.T) {
+	arc := NewARC(3)
+	defer arc.Close()
+	arc.SetPromoteOnWrite(false)
+
+	arc.Put("key1", "value1")
+	arc.Put("key2", "value2")
+	arc.Put("key3", "value3")
+	// 只写不读的键不应被提升为热点
+	for i := 0; i < 5; i++ {
+		arc.Put("key2", "value2")
+	}
+	if arc.t2.Len() != 0 {
+		t.Fatalf("writes should not promote to T2, T2 has %d entries", arc.t2.Len())
+	}
+
+	// 读取过的键才会进入 T2 并在替换中存活
+	arc.Get("key1")
+	arc.Put("key4", "value4")
+	arc.Put("key5", "value5")
+	if _, ok := arc.Get("key1"); !ok {
+		t.Error("read key1 should survive replacement")
+	}
+	if _, ok := arc.Get("key2"); ok {
+		t.Error("write-only key2 should be replaced like a cold entry")
+	}
+}
+
+func TestARCOnExpireVeto(t *testing.T) {
+	arc := NewARC(3)
+	defer arc.Close()
+
+	active := map[string]bool{"session1": true}
+	var renewed []string
+	arc.SetOnExpire(func(key string, value any) (bool, time.Duration) {
+		if active[key] {
+			renewed = append(renewed, key)
+			return true, time.Hour
+		}
+		return false, 0
+	})
+
+	arc.PutWithTTL("session1", "alice", 10*time.Millisecond)
+	arc.PutWithTTL("session2", "bob", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if n := arc.EvictExpired(); n != 1 {
+		t.Errorf("only the inactive session should be evicted, got %d", n)
+	}
+	if v, ok := arc.Get("session1"); !ok || v != "alice" {
+		t.Errorf("vetoed session1 should be kept, got %v", v)
+	}
+	if _, ok := arc.Get("session2"); ok {
+		t.Error("session2 should be expired")
+	}
+	if len(renewed) != 1 || renewed[0] != "session1" {
+		t.Errorf("expect session1 to be renewed once, got %v", renewed)
+	}
+}
+
+func TestARCAdaptiveP(t *testing.T) {
+	arc := NewARC(2)
+	defer arc.Close()
+
+	arc.Put("a", 1)
+	arc.Put("b", 2)
+	arc.Put("c", 3) // T1 超过 p，淘汰 a 进入 B1
+	if s := arc.State(); s != (ARCState{P: 0, T1: 2, B1: 1}) {
+		t.Fatalf("unexpected state after filling T1: %+v", s)
+	}
+
+	// 命中 B1：增大 p，a 直接进入 T2
+	arc.Put("a", 1)
+	if s := arc.State(); s != (ARCState{P: 1, T1: 1, T2: 1, B1: 1}) {
+		t.Fatalf("expect p to grow after a B1 hit: %+v", s)
+	}
+
+	// T1 的长度不超过 p，淘汰 T2 中的 a 进入 B2
+	arc.Put("d", 4)
+	if _, ok := arc.Get("a"); ok {
+		t.Fatal("a should be evicted from T2")
+	}
+	if s := arc.State(); s != (ARCState{P: 1, T1: 2, B1: 1, B2: 1}) {
+		t.Fatalf("unexpected state after evicting from T2: %+v", s)
+	}
+
+	// 命中 B2：减小 p
+	arc.Put("a", 1)
+	if s := arc.State(); s.P != 0 || s.T2 != 1 {
+		t.Fatalf("expect p to shrink after a B2 hit: %+v", s)
+	}
+
+	// 强制设置 p，超出范围时返回错误且不生效
+	if err := arc.SetP(2); err != nil || arc.State().P != 2 {
+		t.Fatalf("expect p set to 2, got %d (err=%v)", arc.State().P, err)
+	}
+	if err := arc.SetP(3); err == nil || arc.State().P != 2 {
+		t.Fatalf("expect an error for p above capacity, got p=%d (err=%v)", arc.State().P, err)
+	}
+	if err := arc.SetP(-1); err == nil {
+		t.Fatal("expect an error for negative p")
+	}
+}
+
+func TestARCFreezeP(t *testing.T) {
+	// 固定 p 时，相同的访问序列得到相同的命中次数
+	run := func() (hits int, p int) {
+		arc := NewARC(4)
+		defer arc.Close()
+		arc.SetP(1)
+		arc.FreezeP(true)
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprintf("key%d", (i*7)%11)
+			if _, ok := arc.Get(key); ok {
+				hits++
+			} else {
+				arc.Put(key, i)
+			}
+		}
+		return hits, arc.State().P
+	}
+	hits, p := run()
+	if p != 1 {
+		t.Fatalf("frozen p should not move on ghost hits, got %d", p)
+	}
+	if again, _ := run(); again != hits {
+		t.Fatalf("expect reproducible hits with p frozen, got %d and %d", hits, again)
+	}
+
+	// 冻结后历史记录命中不改变 p，解冻后恢复自适应
+	arc := NewARC(2)
+	defer arc.Close()
+	arc.FreezeP(true)
+	arc.Put("a", 1)
+	arc.Put("b", 2)
+	arc.Put("c", 3) // a 进入 B1
+	arc.Put("a", 1) // 命中 B1
+	if s := arc.State(); s.P != 0 || s.T2 != 1 {
+		t.Fatalf("expect p unchanged by a B1 hit while frozen: %+v", s)
+	}
+	arc.FreezeP(false)
+	arc.Put("d", 4)
+	arc.Put("b", 2) // 命中 B1
+	if p := arc.State().P; p == 0 {
+		t.Fatal("expect p to adapt again after unfreezing")
+	}
+}
+
+func TestARCKeysAndStats(t *testing.T) {
+	arc := NewARC(3)
+	defer arc.Close()
+
+	arc.Put("a", 1)
+	arc.Put("b", 2)
+	arc.Put("c", 3)
+	arc.Get("a")       // 命中，a 提升到 T2
+	arc.Get("missing") // 未命中
+	arc.Put("d", 4)    // 淘汰 T1 中的 b 进入 B1
+
+	if keys := arc.Keys(); !reflect.DeepEqual(keys, []string{"d", "c", "a"}) {
+		t.Fatalf("expect keys [d c a] (T1 then T2), got %v", keys)
+	}
+	want := ARCStats{Hits: 1, Misses: 1, ARCState: ARCState{P: 0, T1: 2, T2: 1, B1: 1}}
+	if s := arc.Stats(); s != want {
+		t.Fatalf("expect stats %+v, got %+v", want, s)
+	}
+
+	arc.Put("b", 2) // 命中 B1，p 增大
+	arc.Get("b")
+	if s := arc.Stats(); s.Hits != 2 || s.P != 1 || s.T2 != 2 {
+		t.Fatalf("expect a hit on b in T2 and p=1 after the B1 hit, got %+v", s)
+	}
+}
+
+func TestARCPeek(t *testing.T) {
+	arc := NewARC(3)
+	defer arc.Close()
+
+	arc.Put("a", 1)
+	for i := 0; i < 5; i++ {
+		if v, ok := arc.Peek("a"); !ok || v != 1 {
+			t.Fatalf("expect a=1, got %v", v)
+		}
+	}
+	if s := arc.Stats(); s.T1 != 1 || s.T2 != 0 || s.Hits != 0 {
+		t.Fatalf("Peek should leave a in T1 without counting hits: %+v", s)
+	}
+
+	arc.PutWithTTL("b", 2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := arc.Peek("b"); ok {
+		t.Fatal("Peek should not return expired entries")
+	}
+	if arc.Size() != 2 {
+		t.Fatalf("Peek should not remove expired entries, size %d", arc.Size())
+	}
+}
+
+func TestARCOnEvicted(t *testing.T) {
+	arc := NewARC(2)
+	defer arc.Close()
+	var evicted []string
+	arc.OnEvicted = func(key string, value interface{}) {
+		evicted = append(evicted, fmt.Sprintf("%s=%v", key, value))
+	}
+
+	arc.Put("a", 1)
+	arc.Put("b", 2)
+	arc.Put("a", 10) // 覆盖已存在的键不算离开缓存，a 被提升到 T2
+	arc.Put("c", 3)  // b 从 T1 移入 B1，值被丢弃
+	arc.Put("b", 20) // 命中 B1 的历史记录不再通知 b，a 从 T2 移入 B2
+	arc.Remove("c")
+	arc.PutWithTTL("d", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	arc.EvictExpired()
+	arc.Clear()
+
+	want := []string{"b=2", "a=10", "c=3", "d=4", "b=20"}
+	if !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+}
+
+func TestARCPolicyBytes(t *testing.T) {
+	p := NewARCPolicy(2)
+	p.Add("k1", String("1"))
+	p.Add("k2", String("22"))
+	p.Add("k1", String("111")) // 覆盖已有的键
+	p.Add("k3", String("3"))   // 容量已满，淘汰一个缓存项
+	var want int64
+	for _, key := range p.Keys() {
+		v, _ := p.Peek(key)
+		want += int64(len(key) + v.Len())
+	}
+	if p.Len() != 2 || p.Bytes() != want {
+		t.Fatalf("expect 2 entries using %d bytes, got %d entries using %d bytes", want, p.Len(), p.Bytes())
+	}
+}
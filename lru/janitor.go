@@ -0,0 +1,89 @@
+package lru
+
+import (
+	"sync"
+	"time"
+)
+
+// Expirer 是可以由Janitor清理的TTL缓存，EvictExpired会在Janitor的协程中调用，需要是并发安全的
+type Expirer interface {
+	EvictExpired() int // 清理所有过期条目，返回清理的数量
+}
+
+// Janitor 在单个协程中按固定周期清理所有注册的TTL缓存
+// 每个ARC默认拥有自己的清理协程，在有成百上千个缓存的部署中会产生大量协程和定时器；
+// 使用共享的Janitor后，所有缓存只由一个协程、一个定时器统一清理
+type Janitor struct {
+	mu       sync.Mutex
+	interval time.Duration        // 清理周期
+	caches   map[Expirer]struct{} // 已注册的缓存
+	stopCh   chan struct{}        // 停止清理的通道
+	stopOnce sync.Once            // 保证Stop只关闭一次通道
+}
+
+// NewJanitor 创建并启动一个共享的清理器
+func NewJanitor(interval time.Duration) *Janitor {
+	j := &Janitor{
+		interval: interval,
+		caches:   make(map[Expirer]struct{}),
+		stopCh:   make(chan struct{}),
+	}
+	go j.run()
+	return j
+}
+
+// run 定期清理所有注册的缓存
+func (j *Janitor) run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.sweep()
+		case <-j.stopCh:
+			return
+		}
+	}
+}
+
+// sweep 对所有注册的缓存执行一次过期清理
+func (j *Janitor) sweep() {
+	// 先复制一份列表，避免清理期间持有Janitor的锁
+	j.mu.Lock()
+	caches := make([]Expirer, 0, len(j.caches))
+	for c := range j.caches {
+		caches = append(caches, c)
+	}
+	j.mu.Unlock()
+
+	for _, c := range caches {
+		c.EvictExpired()
+	}
+}
+
+// Register 注册一个需要清理的缓存，重复注册没有影响
+func (j *Janitor) Register(c Expirer) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.caches[c] = struct{}{}
+}
+
+// Unregister 注销一个缓存，之后它不再被清理
+func (j *Janitor) Unregister(c Expirer) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.caches, c)
+}
+
+// Len 返回当前注册的缓存数量
+func (j *Janitor) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.caches)
+}
+
+// Stop 停止清理协程，已注册的缓存不再被清理
+func (j *Janitor) Stop() {
+	j.stopOnce.Do(func() { close(j.stopCh) })
+}
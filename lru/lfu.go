@@ -0,0 +1,186 @@
+package lru
+
+import "container/list"
+
+// LFU 是一个按访问频率淘汰的缓存，接口与Cache相同。注意：它不是并发安全的。
+//
+// 每个缓存项记录被访问的次数，同一访问次数的缓存项放在同一个链表中并按最近访问排序，
+// 各访问次数的链表再按次数从小到大串成一个链表，淘汰时移除第一个链表中最久未使用的缓存项。
+// 访问次数加1时缓存项只会移到相邻的次数链表，因此Add、Get和淘汰都是O(1)。
+// 与LRU相比，一次性扫描大量key不会把被频繁访问的热点key挤出缓存
+type LFU struct {
+	maxBytes  int64                         // 缓存的最大内存占用（字节），0表示不限制
+	nbytes    int64                         // 当前缓存已使用的内存（字节）
+	cache     map[string]*list.Element      // 键到次数链表中节点的映射
+	freqs     *list.List                    // 按访问次数从小到大排列的*freqNode，只包含非空的次数
+	OnEvicted func(key string, value Value) // 可选的回调函数，当缓存项被清除时调用
+}
+
+// freqNode 是某个访问次数下的所有缓存项
+type freqNode struct {
+	freq  int
+	items *list.List // 访问次数为freq的缓存项，最近访问的在前端
+}
+
+// lfuEntry 是存储在LFU链表中的缓存项
+type lfuEntry struct {
+	key   string
+	value Value
+	node  *list.Element // 所在的次数节点，位于LFU.freqs中
+}
+
+// NewLFU 是LFU的构造函数，maxBytes为0表示不限制内存
+func NewLFU(maxBytes int64, onEvicted func(string, Value)) *LFU {
+	return &LFU{
+		maxBytes:  maxBytes,
+		cache:     make(map[string]*list.Element),
+		freqs:     list.New(),
+		OnEvicted: onEvicted,
+	}
+}
+
+// Add 向缓存中添加一个值，更新已存在的键同样计为一次访问
+// 写入新键之前先淘汰缓存项腾出空间，避免访问次数为1的新键刚写入就被淘汰。
+// 键和值的大小超过maxBytes时不会被缓存：它立即被淘汰（调用OnEvicted），
+// 该键原有的缓存项被删除，其他缓存项不受影响
+func (c *LFU) Add(key string, value Value) {
+	size := int64(len(key)) + int64(value.Len())
+	if c.maxBytes != 0 && size > c.maxBytes {
+		if ele, ok := c.cache[key]; ok {
+			c.remove(ele)
+		}
+		if c.OnEvicted != nil {
+			c.OnEvicted(key, value)
+		}
+		return
+	}
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*lfuEntry)
+		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		kv.value = value
+		c.touch(ele)
+		for c.maxBytes != 0 && c.maxBytes < c.nbytes && len(c.cache) > 0 {
+			c.RemoveOldest()
+		}
+		return
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes+size && len(c.cache) > 0 {
+		c.RemoveOldest()
+	}
+	front := c.freqs.Front()
+	if front == nil || front.Value.(*freqNode).freq != 1 {
+		front = c.freqs.PushFront(&freqNode{freq: 1, items: list.New()})
+	}
+	kv := &lfuEntry{key: key, value: value, node: front}
+	c.cache[key] = front.Value.(*freqNode).items.PushFront(kv)
+	c.nbytes += size
+}
+
+// Get 查找键对应的值，命中时增加其访问次数
+func (c *LFU) Get(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		c.touch(ele)
+		return ele.Value.(*lfuEntry).value, true
+	}
+	return
+}
+
+// Peek 查找键对应的值，不增加其访问次数
+func (c *LFU) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*lfuEntry).value, true
+	}
+	return
+}
+
+// Keys 返回所有的键，按访问次数从多到少排列，次数相同时最近使用的在前，即最先被淘汰的在最后
+func (c *LFU) Keys() []string {
+	keys := make([]string, 0, len(c.cache))
+	for node := c.freqs.Back(); node != nil; node = node.Prev() {
+		for ele := node.Value.(*freqNode).items.Front(); ele != nil; ele = ele.Next() {
+			keys = append(keys, ele.Value.(*lfuEntry).key)
+		}
+	}
+	return keys
+}
+
+// SetOnEvicted 设置OnEvicted，用于通过Policy接口设置回调
+func (c *LFU) SetOnEvicted(f func(key string, value Value)) {
+	c.OnEvicted = f
+}
+
+// RemoveOldest 淘汰访问次数最少的缓存项，次数相同时淘汰最久未使用的
+// 方法名与Cache保持一致，便于两者互相替换
+func (c *LFU) RemoveOldest() {
+	front := c.freqs.Front()
+	if front == nil {
+		return
+	}
+	kv := c.remove(front.Value.(*freqNode).items.Back())
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
+
+// Delete 删除指定键的缓存项，返回该键是否存在，删除时同样会调用OnEvicted
+func (c *LFU) Delete(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	kv := c.remove(ele)
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+	return true
+}
+
+// Clear 清空缓存，按淘汰顺序对每个被丢弃的缓存项调用OnEvicted
+func (c *LFU) Clear() {
+	for len(c.cache) > 0 {
+		c.RemoveOldest()
+	}
+}
+
+// remove 删除缓存项并返回它，不调用OnEvicted
+func (c *LFU) remove(ele *list.Element) *lfuEntry {
+	kv := ele.Value.(*lfuEntry)
+	c.unlink(ele)
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	return kv
+}
+
+// touch 将缓存项的访问次数加1，移到相邻的次数节点的前端，次数节点不存在时在当前节点之后创建
+func (c *LFU) touch(ele *list.Element) {
+	kv := ele.Value.(*lfuEntry)
+	cur := kv.node
+	freq := cur.Value.(*freqNode).freq + 1
+	next := cur.Next()
+	if next == nil || next.Value.(*freqNode).freq != freq {
+		next = c.freqs.InsertAfter(&freqNode{freq: freq, items: list.New()}, cur)
+	}
+	c.unlink(ele)
+	kv.node = next
+	c.cache[kv.key] = next.Value.(*freqNode).items.PushFront(kv)
+}
+
+// unlink 将节点从所在的次数链表中移除，次数链表因此变空时删除该次数节点
+func (c *LFU) unlink(ele *list.Element) {
+	node := ele.Value.(*lfuEntry).node
+	items := node.Value.(*freqNode).items
+	items.Remove(ele)
+	if items.Len() == 0 {
+		c.freqs.Remove(node)
+	}
+}
+
+// Bytes 返回缓存当前占用的内存（字节），包括键和值
+func (c *LFU) Bytes() int64 {
+	return c.nbytes
+}
+
+// Len 返回缓存中的元素个数
+func (c *LFU) Len() int {
+	return len(c.cache)
+}
@@ -0,0 +1,110 @@
+package lru
+
+import "testing"
+
+func TestLFUGet(t *testing.T) {
+	lfu := NewLFU(0, nil)
+	lfu.Add("key1", String("1234"))
+	if v, ok := lfu.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := lfu.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+	lfu.Add("key1", String("12"))
+	if lfu.Bytes() != int64(len("key1")+len("12")) || lfu.Len() != 1 {
+		t.Fatalf("expect bytes updated on overwrite, got %d bytes and %d entries", lfu.Bytes(), lfu.Len())
+	}
+}
+
+func TestLFUKeepsFrequentKey(t *testing.T) {
+	// 容量只能容纳两个缓存项
+	const maxBytes = int64(len("hot") + len("v1") + len("k1") + len("v1"))
+	var evicted []string
+	lfu := NewLFU(maxBytes, func(key string, value Value) { evicted = append(evicted, key) })
+	lru := New(maxBytes, nil)
+
+	for _, c := range []interface {
+		Add(string, Value)
+		Get(string) (Value, bool)
+	}{lfu, lru} {
+		c.Add("hot", String("v1"))
+		for i := 0; i < 5; i++ {
+			c.Get("hot")
+		}
+		// 一次性写入的key超过容量
+		c.Add("k1", String("v1"))
+		c.Add("k2", String("v2"))
+	}
+
+	if _, ok := lfu.Get("hot"); !ok {
+		t.Fatal("LFU should keep the frequently accessed key")
+	}
+	if _, ok := lfu.Get("k2"); !ok || len(evicted) != 1 || evicted[0] != "k1" {
+		t.Fatalf("LFU should evict the one-shot key k1, evicted %v", evicted)
+	}
+	if _, ok := lru.Get("hot"); ok {
+		t.Fatal("LRU is expected to evict the hot key after two newer writes")
+	}
+}
+
+func TestLFURemoveOldest(t *testing.T) {
+	lfu := NewLFU(0, nil)
+	lfu.Add("a", String("1"))
+	lfu.Add("b", String("2"))
+	lfu.Add("c", String("3"))
+	for i := 0; i < 3; i++ {
+		lfu.Get("a")
+	}
+	lfu.Get("c")
+
+	// 访问次数：b=1, c=2, a=4，次数之间有空缺
+	for _, want := range []string{"b", "c", "a"} {
+		lfu.RemoveOldest()
+		if _, ok := lfu.cache[want]; ok {
+			t.Fatalf("expect %s to be evicted next", want)
+		}
+	}
+	if lfu.Len() != 0 || lfu.Bytes() != 0 {
+		t.Fatalf("expect empty cache, got %d entries and %d bytes", lfu.Len(), lfu.Bytes())
+	}
+	lfu.RemoveOldest() // 空缓存不应panic
+}
+
+func TestLFUOversize(t *testing.T) {
+	var evicted []string
+	lfu := NewLFU(10, func(key string, value Value) { evicted = append(evicted, key) })
+	lfu.Add("a", String("1"))
+	lfu.Add("b", String("2"))
+
+	// 超过容量的值不会被缓存，也不会挤掉其他缓存项
+	lfu.Add("big", String("0123456789"))
+	if _, ok := lfu.Get("big"); ok || lfu.Len() != 2 || lfu.Bytes() > 10 {
+		t.Fatalf("oversize value should be rejected, got %d entries and %d bytes", lfu.Len(), lfu.Bytes())
+	}
+	// 已存在的键被更新为过大的值时删除原有的缓存项
+	lfu.Add("a", String("0123456789"))
+	if _, ok := lfu.Get("a"); ok || lfu.Len() != 1 || lfu.Bytes() != 2 {
+		t.Fatalf("oversize update should drop the key, got %d entries and %d bytes", lfu.Len(), lfu.Bytes())
+	}
+	if len(evicted) != 2 || evicted[0] != "big" || evicted[1] != "a" {
+		t.Fatalf("expect oversize values to be reported as evicted, got %v", evicted)
+	}
+}
+
+func TestLFUFreqNodes(t *testing.T) {
+	lfu := NewLFU(0, nil)
+	lfu.Add("a", String("1"))
+	lfu.Add("b", String("2"))
+	for i := 0; i < 3; i++ {
+		lfu.Get("a")
+	}
+	// 只保留非空的访问次数节点，并按次数从小到大排列
+	var freqs []int
+	for e := lfu.freqs.Front(); e != nil; e = e.Next() {
+		freqs = append(freqs, e.Value.(*freqNode).freq)
+	}
+	if len(freqs) != 2 || freqs[0] != 1 || freqs[1] != 4 {
+		t.Fatalf("expect frequency nodes [1 4], got %v", freqs)
+	}
+}
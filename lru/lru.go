@@ -10,7 +10,7 @@
 // 3. 每次访问或添加缓存项时，将其移至链表前端
 // 4. 当需要淘汰时，移除链表尾部的项（最久未使用）
 //
-// 注意：该实现不是并发安全的。如需在并发环境使用，请添加适当的同步机制。
+// 注意：该实现不是并发安全的。如需在并发环境使用，请使用SyncCache或添加适当的同步机制。
 //
 // 典型用途：
 // - 数据库查询缓存
@@ -19,21 +19,67 @@
 // - 需要内存占用控制的任何缓存场景
 package lru // LRU缓存包
 
-import "container/list" // 导入Go标准库中的双向链表包
+import (
+	"container/list" // 导入Go标准库中的双向链表包
+	"time"
+)
 
-// Cache 是一个LRU（最近最少使用）缓存结构。注意：它不是并发安全的。
-type Cache struct {
-	maxBytes  int64                         // 缓存的最大内存占用（字节）
-	nbytes    int64                         // 当前缓存已使用的内存（字节）
-	ll        *list.List                    // 双向链表，用于维护缓存项的访问顺序
-	cache     map[string]*list.Element      // 字符串到链表节点的映射，用于O(1)时间复杂度查找缓存项
-	OnEvicted func(key string, value Value) // 可选的回调函数，当缓存项被清除时调用
+// Cache 是一个LRU（最近最少使用）缓存结构，V为缓存值的类型。注意：它不是并发安全的。
+// New等构造函数创建值类型为Value接口的缓存，NewWithSizer创建任意值类型的缓存，
+// 后者读取时不需要类型断言
+type Cache[V any] struct {
+	maxBytes  int64                     // 缓存的最大内存占用（字节）
+	maxItems  int                       // 缓存的最大条目数，0表示不限制
+	softBytes int64                     // 软限制，超过后每次Add温和地淘汰，0表示不启用
+	nbytes    int64                     // 当前缓存已使用的内存（字节）
+	sizer     func(V) int               // 计算值所占用的字节数
+	ll        *list.List                // 双向链表，用于维护缓存项的访问顺序
+	cache     map[string]*list.Element  // 字符串到链表节点的映射，用于O(1)时间复杂度查找缓存项
+	OnEvicted func(key string, value V) // 可选的回调函数，当缓存项被清除时调用
+	hits      uint64                    // Get命中次数
+	misses    uint64                    // Get未命中次数
+
+	// OnEvictedReason 可选的回调函数，缓存项被清除或其值被替换时调用，并给出原因
+	// 与OnEvicted同时设置时两者都会被调用；EvictReplaced只会传给OnEvictedReason，
+	// OnEvicted的行为保持不变
+	OnEvictedReason func(key string, value V, reason EvictReason)
+}
+
+// EvictReason 表示缓存项离开缓存的原因
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota // 超过内存或条目数限制被淘汰
+	EvictDeleted                     // 被Delete或Clear显式删除
+	EvictExpired                     // 过期后被删除
+	EvictReplaced                    // 旧值被Add写入的新值替换
+)
+
+// String 返回原因的名称，便于作为指标标签
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictExpired:
+		return "expired"
+	case EvictReplaced:
+		return "replaced"
+	}
+	return "unknown"
 }
 
 // entry 是存储在双向链表中的缓存项
-type entry struct {
-	key   string // 缓存项的键
-	value Value  // 缓存项的值 **任何一个实现了Len()方法的类型**
+type entry[V any] struct {
+	key      string    // 缓存项的键
+	value    V         // 缓存项的值，大小由Cache的sizer计算
+	expireAt time.Time // 过期时间，零值表示永不过期
+}
+
+// expired 判断缓存项是否已过期
+func (e *entry[V]) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && now.After(e.expireAt)
 }
 
 // Value 接口用于计算值所占用的字节数
@@ -41,62 +87,337 @@ type Value interface {
 	Len() int // 返回值所占用的字节数
 }
 
+// valueLen 是Value接口的sizer
+func valueLen(v Value) int {
+	return v.Len()
+}
+
 // New 是Cache的构造函数
-func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
-	return &Cache{
+// maxBytes为0表示不限制内存，缓存项只会被显式删除；maxBytes为负数时每次Add都会淘汰全部缓存项
+func New(maxBytes int64, onEvicted func(string, Value)) *Cache[Value] {
+	return NewWithLimits(maxBytes, 0, onEvicted)
+}
+
+// NewWithLimits 创建同时限制内存和条目数的Cache，适合缓存大量很小的值
+// 添加缓存项后会不断淘汰最久未使用的缓存项，直到两项限制都满足；任一限制为0表示该维度不限制
+func NewWithLimits(maxBytes int64, maxItems int, onEvicted func(string, Value)) *Cache[Value] {
+	c := NewWithSizer(maxBytes, valueLen, onEvicted)
+	c.maxItems = maxItems // 设置最大条目数
+	return c
+}
+
+// NewWithSizer 创建值类型为V的Cache，sizer计算值所占用的字节数
+// 值类型不需要实现Value接口，Get直接返回V而不需要类型断言。maxBytes的含义与New相同
+func NewWithSizer[V any](maxBytes int64, sizer func(V) int, onEvicted func(string, V)) *Cache[V] {
+	return &Cache[V]{
 		maxBytes:  maxBytes,                            // 设置最大内存限制
+		sizer:     sizer,                               // 设置值大小的计算函数
 		ll:        list.New(),                          // 初始化双向链表
 		cache:     make(map[string]*list.Element, 100), // 初始化哈希表
 		OnEvicted: onEvicted,                           // 设置回调函数
 	}
 }
 
-// Add 向缓存中添加一个值
-func (c *Cache) Add(key string, value Value) {
+// Add 向缓存中添加一个值，该值永不过期
+func (c *Cache[V]) Add(key string, value V) {
+	c.AddWithTTL(key, value, 0)
+}
+
+// softEvictScale 决定软限制和硬限制之间的淘汰力度：
+// 超出软限制的部分每占两者差值的1/softEvictScale，每次Add就多淘汰一个缓存项
+const softEvictScale = 4
+
+// NewWithSoftLimit 创建同时具有软限制和硬限制的Cache，用于平滑淘汰带来的延迟
+// 内存占用超过softBytes后，每次Add只淘汰少量最久未使用的缓存项，越接近hardBytes淘汰得越多，
+// 直到回到softBytes以下；超过hardBytes时立即连续淘汰，直到不超过hardBytes。
+// hardBytes为0表示没有硬限制；softBytes为0或不小于hardBytes时等同于New(hardBytes, onEvicted)
+func NewWithSoftLimit(softBytes, hardBytes int64, onEvicted func(string, Value)) *Cache[Value] {
+	c := New(hardBytes, onEvicted)
+	if softBytes > 0 && (hardBytes == 0 || softBytes < hardBytes) {
+		c.softBytes = softBytes
+	}
+	return c
+}
+
+// AddWithTTL 向缓存中添加一个值，ttl后过期，ttl不大于0表示永不过期
+// 更新已存在的键时会按新的ttl重新设置过期时间。过期的缓存项在Get时被删除并调用OnEvicted，
+// 在此之前仍然占用内存，并且会出现在Keys等枚举结果中
+func (c *Cache[V]) AddWithTTL(key string, value V, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
 	if ele, ok := c.cache[key]; ok {
 		// 如果键已存在，更新对应节点的值
-		c.ll.MoveToFront(ele)                                  // 将节点移到链表前端（表示最近访问）
-		kv := ele.Value.(*entry)                               // 获取节点中存储的entry
-		c.nbytes += int64(value.Len()) - int64(kv.value.Len()) // 更新内存占用（新值大小 - 旧值大小）
-		kv.value = value                                       // 更新值
+		c.ll.MoveToFront(ele)                                        // 将节点移到链表前端（表示最近访问）
+		kv := ele.Value.(*entry[V])                                  // 获取节点中存储的entry
+		c.nbytes += int64(c.sizer(value)) - int64(c.sizer(kv.value)) // 更新内存占用（新值大小 - 旧值大小）
+		if c.OnEvictedReason != nil {
+			c.OnEvictedReason(key, kv.value, EvictReplaced)
+		}
+		kv.value = value       // 更新值
+		kv.expireAt = expireAt // 更新过期时间
 	} else {
 		// 如果键不存在，创建新节点
-		ele := c.ll.PushFront(&entry{key, value})        // 在链表前端添加新节点
-		c.cache[key] = ele                               // 在哈希表中记录键到节点的映射
-		c.nbytes += int64(len(key)) + int64(value.Len()) // 更新内存占用（键大小 + 值大小）
+		ele := c.ll.PushFront(&entry[V]{key, value, expireAt}) // 在链表前端添加新节点
+		c.cache[key] = ele                                     // 在哈希表中记录键到节点的映射
+		c.nbytes += int64(len(key)) + int64(c.sizer(value))    // 更新内存占用（键大小 + 值大小）
 	}
-	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
-		// 如果超过最大内存限制，移除最久未使用的节点
+	for c.overLimit() {
+		// 如果超过最大内存或条目数限制，移除最久未使用的节点
 		c.RemoveOldest()
 	}
+	c.evictSoft()
+}
+
+// evictSoft 在内存占用超过软限制时按超出的程度淘汰少量缓存项
+func (c *Cache[V]) evictSoft() {
+	if c.softBytes == 0 || c.nbytes <= c.softBytes {
+		return
+	}
+	n := 1
+	if c.maxBytes > 0 {
+		n += int((c.nbytes - c.softBytes) * softEvictScale / (c.maxBytes - c.softBytes))
+	}
+	for ; n > 0 && c.nbytes > c.softBytes && c.ll.Len() > 0; n-- {
+		c.RemoveOldest()
+	}
+}
+
+// overLimit 判断缓存是否超过内存或条目数限制
+func (c *Cache[V]) overLimit() bool {
+	if c.ll.Len() == 0 {
+		return false
+	}
+	return c.maxBytes != 0 && c.maxBytes < c.nbytes || c.maxItems != 0 && c.ll.Len() > c.maxItems
 }
 
 // Get 查找键对应的值
-func (c *Cache) Get(key string) (value Value, ok bool) {
-	if ele, ok := c.cache[key]; ok {
+func (c *Cache[V]) Get(key string) (value V, ok bool) {
+	if ele, ok := c.cache[key]; ok && ele.Value.(*entry[V]).expired(time.Now()) {
+		// 已过期的缓存项视为不存在，并在此时删除
+		c.removeElement(ele, EvictExpired)
+	} else if ok {
 		// 如果键存在
-		c.ll.MoveToFront(ele)    // 将节点移到链表前端（表示最近访问）
-		kv := ele.Value.(*entry) // 获取节点中存储的entry
-		return kv.value, true    // 返回值和true
+		c.hits++
+		c.ll.MoveToFront(ele)       // 将节点移到链表前端（表示最近访问）
+		kv := ele.Value.(*entry[V]) // 获取节点中存储的entry
+		return kv.value, true       // 返回值和true
 	}
+	c.misses++
 	return // 如果键不存在，返回零值和false
 }
 
+// Peek 查找键对应的值，但不改变访问顺序，已过期的缓存项视为不存在但不会被删除
+// 适用于诊断或统计等不应影响淘汰顺序的读取
+func (c *Cache[V]) Peek(key string) (value V, ok bool) {
+	if ele, ok := c.cache[key]; ok && !ele.Value.(*entry[V]).expired(time.Now()) {
+		return ele.Value.(*entry[V]).value, true
+	}
+	return
+}
+
+// GetOldest 返回最久未使用的缓存项，即下一次RemoveOldest将移除的缓存项，缓存为空时ok为false
+// 不改变访问顺序，也不检查是否过期
+func (c *Cache[V]) GetOldest() (key string, value V, ok bool) {
+	if ele := c.ll.Back(); ele != nil {
+		kv := ele.Value.(*entry[V])
+		return kv.key, kv.value, true
+	}
+	return
+}
+
+// GetNewest 返回最近使用的缓存项，缓存为空时ok为false，不改变访问顺序，也不检查是否过期
+func (c *Cache[V]) GetNewest() (key string, value V, ok bool) {
+	if ele := c.ll.Front(); ele != nil {
+		kv := ele.Value.(*entry[V])
+		return kv.key, kv.value, true
+	}
+	return
+}
+
 // RemoveOldest 移除最久未使用的缓存项
-func (c *Cache) RemoveOldest() {
+func (c *Cache[V]) RemoveOldest() {
 	ele := c.ll.Back() // 获取链表尾部节点（最久未使用的）
 	if ele != nil {
-		c.ll.Remove(ele)                                       // 从链表中删除该节点
-		kv := ele.Value.(*entry)                               // 获取节点中存储的entry
-		delete(c.cache, kv.key)                                // 从哈希表中删除对应的键值对
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新内存占用
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value) // 如果设置了回调函数，调用它
+		c.removeElement(ele, EvictCapacity)
+	}
+}
+
+// Delete 删除指定键的缓存项，返回该键是否存在
+// 删除时同样会调用OnEvicted，键不存在时不做任何操作
+func (c *Cache[V]) Delete(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	c.removeElement(ele, EvictDeleted)
+	return true
+}
+
+// EvictExpired 删除所有已过期的缓存项，返回删除的数量
+// 过期的缓存项平时只在被访问时删除，定期调用EvictExpired可以及时释放不再被访问的过期缓存项
+func (c *Cache[V]) EvictExpired() int {
+	now := time.Now()
+	n := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if ele.Value.(*entry[V]).expired(now) {
+			c.removeElement(ele, EvictExpired)
+			n++
 		}
+		ele = prev
 	}
+	return n
+}
+
+// SetOnEvicted 设置OnEvicted，用于通过Policy接口设置回调
+func (c *Cache[V]) SetOnEvicted(f func(key string, value V)) {
+	c.OnEvicted = f
+}
+
+// Take 删除并返回指定键的缓存项，不调用淘汰回调，用于把缓存项转移到其他缓存
+func (c *Cache[V]) Take(key string) (value V, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	c.ll.Remove(ele)
+	kv := ele.Value.(*entry[V])
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(c.sizer(kv.value))
+	if c.nbytes < 0 {
+		c.nbytes = 0
+	}
+	return kv.value, true
+}
+
+// Clear 清空缓存中的所有缓存项，清空后缓存可以继续使用
+// 设置了OnEvicted或OnEvictedReason时，会按从旧到新的顺序对每个被丢弃的缓存项调用一次，
+// 以便调用方释放与缓存项关联的资源，原因为EvictDeleted
+func (c *Cache[V]) Clear() {
+	for ele := c.ll.Back(); ele != nil; ele = ele.Prev() {
+		kv := ele.Value.(*entry[V])
+		c.evicted(kv, EvictDeleted)
+	}
+	c.ll = list.New()
+	c.cache = make(map[string]*list.Element)
+	c.nbytes = 0
+}
+
+// removeElement 从链表和哈希表中删除节点，更新内存占用并调用淘汰回调
+func (c *Cache[V]) removeElement(ele *list.Element, reason EvictReason) {
+	c.ll.Remove(ele)                                          // 从链表中删除该节点
+	kv := ele.Value.(*entry[V])                               // 获取节点中存储的entry
+	delete(c.cache, kv.key)                                   // 从哈希表中删除对应的键值对
+	c.nbytes -= int64(len(kv.key)) + int64(c.sizer(kv.value)) // 更新内存占用
+	if c.nbytes < 0 {
+		c.nbytes = 0 // 值的Len在写入后发生变化时，避免内存占用变为负数
+	}
+	c.evicted(kv, reason)
+}
+
+// evicted 调用已设置的淘汰回调
+func (c *Cache[V]) evicted(kv *entry[V], reason EvictReason) {
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value) // 如果设置了回调函数，调用它
+	}
+	if c.OnEvictedReason != nil {
+		c.OnEvictedReason(kv.key, kv.value, reason)
+	}
+}
+
+// OldestN 返回最久未使用的n个键，按从旧到新的顺序排列
+// 只从链表尾部向前遍历n个节点，时间复杂度为O(n)，且不会改变访问顺序
+func (c *Cache[V]) OldestN(n int) []string {
+	if n > c.ll.Len() {
+		n = c.ll.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	keys := make([]string, 0, n)
+	for ele := c.ll.Back(); ele != nil && len(keys) < n; ele = ele.Prev() {
+		keys = append(keys, ele.Value.(*entry[V]).key)
+	}
+	return keys
+}
+
+// NewestN 返回最近使用的n个键，按从新到旧的顺序排列
+// 只从链表头部向后遍历n个节点，时间复杂度为O(n)，且不会改变访问顺序
+func (c *Cache[V]) NewestN(n int) []string {
+	if n > c.ll.Len() {
+		n = c.ll.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	keys := make([]string, 0, n)
+	for ele := c.ll.Front(); ele != nil && len(keys) < n; ele = ele.Next() {
+		keys = append(keys, ele.Value.(*entry[V]).key)
+	}
+	return keys
+}
+
+// SampleKeys 返回最多n个随机选取的键，不会改变访问顺序
+// 利用Go map迭代顺序随机的特性，只遍历n个元素，时间复杂度为O(n)
+func (c *Cache[V]) SampleKeys(n int) []string {
+	if n > len(c.cache) {
+		n = len(c.cache)
+	}
+	if n <= 0 {
+		return nil
+	}
+	keys := make([]string, 0, n)
+	for key := range c.cache {
+		keys = append(keys, key)
+		if len(keys) == n {
+			break
+		}
+	}
+	return keys
+}
+
+// Bytes 返回缓存当前占用的内存（字节），包括键和值
+func (c *Cache[V]) Bytes() int64 {
+	return c.nbytes
+}
+
+// Resize 修改最大内存限制并立即淘汰最久未使用的缓存项，直到内存占用不超过新的限制，
+// 返回被淘汰的缓存项数量。maxBytes为0表示不限制内存，不会淘汰任何缓存项
+func (c *Cache[V]) Resize(maxBytes int64) (evicted int) {
+	c.maxBytes = maxBytes
+	for c.overLimit() {
+		c.RemoveOldest()
+		evicted++
+	}
+	return evicted
+}
+
+// Keys 返回缓存中的所有键，按从最近使用到最久未使用的顺序排列
+// 返回的是新分配的切片，不会改变访问顺序
+func (c *Cache[V]) Keys() []string {
+	return c.NewestN(c.ll.Len())
+}
+
+// Range 按从最近使用到最久未使用的顺序对每个缓存项调用f，f返回false时停止遍历
+// 遍历不会改变访问顺序。不支持在f中修改缓存（Add、Get、Delete等），否则遍历结果未定义
+func (c *Cache[V]) Range(f func(key string, value V) bool) {
+	for ele := c.ll.Front(); ele != nil; ele = ele.Next() {
+		kv := ele.Value.(*entry[V])
+		if !f(kv.key, kv.value) {
+			return
+		}
+	}
+}
+
+// Stats 返回Get的命中和未命中次数，Peek不计入
+// 与Cache的其他方法一样不是并发安全的，包装Cache的调用方需要自行加锁
+func (c *Cache[V]) Stats() (hits, misses uint64) {
+	return c.hits, c.misses
 }
 
 // Len 返回缓存中的元素个数
-func (c *Cache) Len() int {
+func (c *Cache[V]) Len() int {
 	return c.ll.Len() // 返回链表长度
 }
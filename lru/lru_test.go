@@ -1,8 +1,11 @@
 package lru
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type String string
@@ -63,3 +66,463 @@ func TestAdd(t *testing.T) {
 		t.Fatal("expected 6 but got", lru.nbytes)
 	}
 }
+
+func TestOldestN(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("k1", String("1"))
+	lru.Add("k2", String("2"))
+	lru.Add("k3", String("3"))
+	lru.Add("k4", String("4"))
+	lru.Get("k1") // k1 变为最近使用
+
+	if got, expect := lru.OldestN(2), []string{"k2", "k3"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("OldestN(2) expect %v, got %v", expect, got)
+	}
+	if got, expect := lru.OldestN(10), []string{"k2", "k3", "k4", "k1"}; !reflect.DeepEqual(got, expect) {
+		t.Fatalf("OldestN(10) expect %v, got %v", expect, got)
+	}
+	if got := lru.OldestN(0); len(got) != 0 {
+		t.Fatalf("OldestN(0) expect empty, got %v", got)
+	}
+
+	// OldestN 不应改变淘汰顺序
+	lru.RemoveOldest()
+	if _, ok := lru.Get("k2"); ok {
+		t.Fatalf("OldestN changed eviction order")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	lru.Add("key1", String("1234"))
+	lru.Add("key2", String("5678"))
+
+	if !lru.Delete("key1") {
+		t.Fatal("Delete key1 should report the key was present")
+	}
+	if _, ok := lru.Get("key1"); ok || lru.Len() != 1 {
+		t.Fatal("key1 should be removed")
+	}
+	if lru.Bytes() != int64(len("key2")+len("5678")) {
+		t.Fatalf("expect nbytes %d, got %d", len("key2")+len("5678"), lru.Bytes())
+	}
+	if !reflect.DeepEqual(evicted, []string{"key1"}) {
+		t.Fatalf("OnEvicted should be called for key1, got %v", evicted)
+	}
+
+	// 删除不存在的键不做任何操作
+	if lru.Delete("missing") || lru.Len() != 1 || len(evicted) != 1 {
+		t.Fatal("Delete of a missing key should be a no-op")
+	}
+	lru.Delete("key2")
+	if lru.Bytes() != 0 {
+		t.Fatalf("nbytes should be 0 after deleting everything, got %d", lru.Bytes())
+	}
+}
+
+func TestClear(t *testing.T) {
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	lru.Add("key1", String("1"))
+	lru.Add("key2", String("2"))
+	lru.Clear()
+
+	if lru.Len() != 0 || lru.Bytes() != 0 {
+		t.Fatalf("expect empty cache, got len %d and %d bytes", lru.Len(), lru.Bytes())
+	}
+	if !reflect.DeepEqual(evicted, []string{"key1", "key2"}) {
+		t.Fatalf("OnEvicted should fire for every discarded entry, got %v", evicted)
+	}
+
+	// 清空后仍然可以正常使用
+	lru.Add("key3", String("3"))
+	if v, ok := lru.Get("key3"); !ok || string(v.(String)) != "3" {
+		t.Fatal("cache should be usable after Clear")
+	}
+	if _, ok := lru.Get("key1"); ok {
+		t.Fatal("key1 should be gone after Clear")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("key1", String("1"))
+	lru.Add("key2", String("2"))
+	lru.Add("key3", String("3"))
+
+	for i := 0; i < 3; i++ {
+		if v, ok := lru.Peek("key1"); !ok || string(v.(String)) != "1" {
+			t.Fatal("Peek key1 failed")
+		}
+	}
+	if _, ok := lru.Peek("missing"); ok {
+		t.Fatal("Peek of a missing key should miss")
+	}
+
+	// Peek不改变访问顺序，key1仍是最久未使用的
+	lru.RemoveOldest()
+	if _, ok := lru.Peek("key1"); ok {
+		t.Fatal("RemoveOldest should still evict key1 after Peek")
+	}
+}
+
+func TestStats(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("key1", String("1"))
+	lru.Get("key1")
+	lru.Get("key1")
+	lru.Get("missing")
+	lru.Peek("key1")
+	if hits, misses := lru.Stats(); hits != 2 || misses != 1 {
+		t.Fatalf("expect 2 hits and 1 miss, got %d and %d", hits, misses)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	lru := New(int64(0), nil)
+	if keys := lru.Keys(); len(keys) != 0 {
+		t.Fatalf("expect no keys, got %v", keys)
+	}
+	lru.Add("key1", String("1"))
+	lru.Add("key2", String("2"))
+	lru.Add("key3", String("3"))
+	lru.Get("key2")
+
+	keys := lru.Keys()
+	if !reflect.DeepEqual(keys, []string{"key2", "key3", "key1"}) {
+		t.Fatalf("expect [key2 key3 key1], got %v", keys)
+	}
+	// 修改返回的切片不影响缓存
+	keys[0] = "corrupted"
+	if !reflect.DeepEqual(lru.Keys(), []string{"key2", "key3", "key1"}) {
+		t.Fatal("Keys should return a fresh copy")
+	}
+}
+
+func TestGetOldestNewest(t *testing.T) {
+	var removed string
+	lru := New(int64(0), func(key string, value Value) { removed = key })
+	if _, _, ok := lru.GetOldest(); ok {
+		t.Fatal("empty cache should have no oldest entry")
+	}
+	lru.Add("key1", String("1"))
+	lru.Add("key2", String("2"))
+	lru.Add("key3", String("3"))
+	lru.Get("key1")
+
+	if key, value, ok := lru.GetNewest(); !ok || key != "key1" || value.(String) != "1" {
+		t.Fatalf("expect newest key1=1, got %s=%v", key, value)
+	}
+	for lru.Len() > 0 {
+		oldest, _, _ := lru.GetOldest()
+		lru.RemoveOldest()
+		if removed != oldest {
+			t.Fatalf("GetOldest reported %s but RemoveOldest removed %s", oldest, removed)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	lru := New(int64(0), nil)
+	for i := 1; i <= 5; i++ {
+		lru.Add(fmt.Sprintf("key%d", i), String(strings.Repeat("v", i)))
+	}
+
+	// 从最近使用的开始收集，遇到长度不超过3的值后停止
+	var keys []string
+	lru.Range(func(key string, value Value) bool {
+		keys = append(keys, key)
+		return value.Len() > 3
+	})
+	if !reflect.DeepEqual(keys, []string{"key5", "key4", "key3"}) {
+		t.Fatalf("expect [key5 key4 key3], got %v", keys)
+	}
+	// 遍历不改变访问顺序
+	if !reflect.DeepEqual(lru.Keys(), []string{"key5", "key4", "key3", "key2", "key1"}) {
+		t.Fatalf("Range should not change the access order, got %v", lru.Keys())
+	}
+}
+
+func TestNewWithLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		maxItems int
+		expect   []string
+	}{
+		{"byte-only", int64(len("k1v1k2v2k3v3")), 0, []string{"k4", "k3", "k2"}},
+		{"count-only", 0, 2, []string{"k4", "k3"}},
+		{"combined-bytes", int64(len("k1v1k2v2")), 3, []string{"k4", "k3"}},
+		{"combined-items", int64(len("k1v1k2v2k3v3k4v4")), 1, []string{"k4"}},
+		{"unbounded", 0, 0, []string{"k4", "k3", "k2", "k1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lru := NewWithLimits(tt.maxBytes, tt.maxItems, nil)
+			for _, k := range []string{"k1", "k2", "k3", "k4"} {
+				lru.Add(k, String("v"+k[1:]))
+			}
+			if keys := lru.Keys(); !reflect.DeepEqual(keys, tt.expect) {
+				t.Fatalf("expect %v, got %v", tt.expect, keys)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	for _, k := range []string{"k1", "k2", "k3", "k4"} {
+		lru.Add(k, String("v"+k[1:]))
+	}
+	lru.Get("k1") // k1变为最近使用
+
+	if n := lru.Resize(int64(len("k1v1k4v4"))); n != 2 {
+		t.Fatalf("expect 2 evictions, got %d", n)
+	}
+	if !reflect.DeepEqual(evicted, []string{"k2", "k3"}) {
+		t.Fatalf("expect k2 and k3 evicted in LRU order, got %v", evicted)
+	}
+	if keys := lru.Keys(); !reflect.DeepEqual(keys, []string{"k1", "k4"}) {
+		t.Fatalf("expect [k1 k4] to remain, got %v", keys)
+	}
+
+	// 新的限制对之后的写入同样生效
+	lru.Add("k5", String("v5"))
+	if lru.Len() != 2 {
+		t.Fatalf("expect the new limit to hold, got %d entries", lru.Len())
+	}
+
+	if n := lru.Resize(0); n != 0 {
+		t.Fatalf("unbounded resize should not evict, got %d", n)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	var evicted []string
+	lru := New(int64(0), func(key string, value Value) {
+		evicted = append(evicted, key)
+	})
+	lru.AddWithTTL("key1", String("1"), 50*time.Millisecond)
+	lru.AddWithTTL("key2", String("2"), 0) // 永久缓存
+	lru.AddWithTTL("key3", String("3"), -time.Second)
+
+	if _, ok := lru.Get("key1"); !ok {
+		t.Fatal("key1 should not be expired yet")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := lru.Peek("key1"); ok {
+		t.Fatal("Peek should treat expired key1 as missing")
+	}
+	if _, ok := lru.Get("key1"); ok {
+		t.Fatal("key1 should be expired")
+	}
+	if !reflect.DeepEqual(evicted, []string{"key1"}) || lru.Len() != 2 {
+		t.Fatalf("expired key1 should be removed and evicted, got %v", evicted)
+	}
+	if lru.Bytes() != int64(len("key2")+len("2")+len("key3")+len("3")) {
+		t.Fatalf("unexpected nbytes %d after expiry", lru.Bytes())
+	}
+
+	// 永久缓存与带TTL的缓存项共存，负的TTL同样表示永不过期
+	if v, ok := lru.Get("key2"); !ok || string(v.(String)) != "2" {
+		t.Fatal("permanent key2 should remain")
+	}
+	if _, ok := lru.Get("key3"); !ok {
+		t.Fatal("negative ttl should mean no expiry")
+	}
+}
+
+func TestTTLUpdate(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.AddWithTTL("key1", String("1"), 50*time.Millisecond)
+	lru.AddWithTTL("key1", String("1"), 150*time.Millisecond) // 更新延长TTL
+
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := lru.Get("key1"); !ok {
+		t.Fatal("updated key1 should still exist")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := lru.Get("key1"); ok {
+		t.Fatal("key1 should be expired after the extended TTL")
+	}
+
+	// 使用Add更新会清除过期时间
+	lru.AddWithTTL("key2", String("2"), 20*time.Millisecond)
+	lru.Add("key2", String("2"))
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := lru.Get("key2"); !ok {
+		t.Fatal("Add should make key2 permanent")
+	}
+}
+
+func TestSoftLimit(t *testing.T) {
+	evictions := 0
+	lru := NewWithSoftLimit(100, 200, func(key string, value Value) { evictions++ })
+	for i := 0; i < 10; i++ {
+		lru.Add(fmt.Sprintf("k%02d", i), String("1234567")) // 每项10字节
+	}
+	if evictions != 0 || lru.Bytes() != 100 {
+		t.Fatalf("no eviction expected up to the soft limit, got %d evictions and %d bytes", evictions, lru.Bytes())
+	}
+
+	// 略微超过软限制：温和地淘汰一项
+	lru.Add("k10", String("1234567"))
+	if evictions != 1 || lru.Bytes() != 100 {
+		t.Fatalf("expect 1 eviction just past the soft limit, got %d evictions and %d bytes", evictions, lru.Bytes())
+	}
+
+	// 离硬限制越近淘汰越多，但不会一次回到软限制以下
+	evictions = 0
+	lru.Add("big", String(strings.Repeat("x", 57))) // 60字节，占用160
+	if evictions != 3 || lru.Bytes() != 130 {
+		t.Fatalf("expect 3 evictions deep into the soft band, got %d evictions and %d bytes", evictions, lru.Bytes())
+	}
+
+	// 超过硬限制时连续淘汰
+	evictions = 0
+	lru.Add("huge", String(strings.Repeat("x", 146))) // 150字节，占用280
+	if lru.Bytes() > 200 || evictions < 8 {
+		t.Fatalf("expect aggressive eviction above the hard limit, got %d evictions and %d bytes", evictions, lru.Bytes())
+	}
+}
+
+func TestOnEvictedReason(t *testing.T) {
+	var reasons []EvictReason
+	legacy := 0
+	lru := New(int64(8), func(key string, value Value) { legacy++ })
+	lru.OnEvictedReason = func(key string, value Value, reason EvictReason) {
+		reasons = append(reasons, reason)
+	}
+
+	lru.Add("k1", String("v1"))
+	lru.Add("k1", String("v2")) // 替换
+	lru.Add("k2", String("v2"))
+	lru.Add("k3", String("v3")) // 超过容量，淘汰k1
+	lru.Delete("k2")
+	lru.AddWithTTL("k4", String("v4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	lru.Get("k4") // 过期
+	lru.Clear()   // 清空k3
+
+	want := []EvictReason{EvictReplaced, EvictCapacity, EvictDeleted, EvictExpired, EvictDeleted}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Fatalf("expect reasons %v, got %v", want, reasons)
+	}
+	// 旧回调不会因为替换而被调用
+	if legacy != len(want)-1 {
+		t.Fatalf("expect OnEvicted called %d times, got %d", len(want)-1, legacy)
+	}
+}
+
+func TestNewWithSizer(t *testing.T) {
+	type user struct {
+		name string
+		age  int
+	}
+	var evicted []user
+	c := NewWithSizer(int64(12), func(u user) int { return len(u.name) },
+		func(key string, u user) { evicted = append(evicted, u) })
+
+	c.Add("u1", user{"alice", 30}) // 7字节
+	c.Add("u2", user{"bob", 25})   // 5字节
+	if u, ok := c.Get("u1"); !ok || u.age != 30 {
+		t.Fatalf("expect alice, got %+v", u)
+	}
+	if c.Bytes() != 12 {
+		t.Fatalf("expect sizer to be used for byte accounting, got %d bytes", c.Bytes())
+	}
+	c.Add("u3", user{"c", 1}) // 超过限制，淘汰最久未使用的u2
+	if len(evicted) != 1 || evicted[0].name != "bob" {
+		t.Fatalf("expect bob to be evicted, got %+v", evicted)
+	}
+}
+
+func BenchmarkGetInterface(b *testing.B) {
+	c := New(0, nil)
+	c.Add("key", String("value"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, _ := c.Get("key")
+		_ = v.(String)
+	}
+}
+
+func BenchmarkGetGeneric(b *testing.B) {
+	c := NewWithSizer(0, func(s String) int { return len(s) }, nil)
+	c.Add("key", String("value"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, _ := c.Get("key")
+		_ = v
+	}
+}
+
+func TestPolicyDelete(t *testing.T) {
+	policies := map[string]Policy{
+		"lru":  New(0, nil),
+		"lfu":  NewLFU(0, nil),
+		"slru": NewSLRU(0, 0, nil),
+		"arc":  NewARCPolicy(10),
+	}
+	for name, p := range policies {
+		var evicted []string
+		p.SetOnEvicted(func(key string, _ Value) { evicted = append(evicted, key) })
+		p.Add("a", String("1"))
+		p.Add("b", String("22"))
+		p.Add("c", String("333"))
+		if v, ok := p.Peek("b"); !ok || string(v.(String)) != "22" {
+			t.Fatalf("%s: Peek returned %v, %v", name, v, ok)
+		}
+		if !p.Delete("a") || p.Delete("a") {
+			t.Fatalf("%s: Delete should report whether the key existed", name)
+		}
+		if _, ok := p.Get("a"); ok || p.Len() != 2 || p.Bytes() != 7 {
+			t.Fatalf("%s: unexpected state after Delete: len=%d bytes=%d", name, p.Len(), p.Bytes())
+		}
+		if keys := p.Keys(); len(keys) != 2 {
+			t.Fatalf("%s: expect 2 keys, got %v", name, keys)
+		}
+		p.RemoveOldest()
+		if p.Len() != 1 || len(evicted) != 2 {
+			t.Fatalf("%s: RemoveOldest should evict one entry, len=%d evicted=%v", name, p.Len(), evicted)
+		}
+		p.Clear()
+		if p.Len() != 0 || p.Bytes() != 0 || len(evicted) != 3 {
+			t.Fatalf("%s: expect empty after Clear, len=%d bytes=%d evicted=%v", name, p.Len(), p.Bytes(), evicted)
+		}
+	}
+}
+
+func TestTake(t *testing.T) {
+	var evicted []string
+	lru := New(0, func(key string, _ Value) { evicted = append(evicted, key) })
+	lru.Add("key1", String("1234"))
+	if v, ok := lru.Take("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("Take returned %v, %v", v, ok)
+	}
+	if lru.Len() != 0 || lru.Bytes() != 0 || len(evicted) != 0 {
+		t.Fatalf("Take should remove the entry without calling OnEvicted, len=%d evicted=%v", lru.Len(), evicted)
+	}
+}
+
+func TestEvictExpired(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.AddWithTTL("key1", String("1"), time.Millisecond)
+	lru.AddWithTTL("key2", String("2"), 0)
+	lru.AddWithTTL("key3", String("3"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if n := lru.EvictExpired(); n != 2 || lru.Len() != 1 {
+		t.Fatalf("expect 2 expired entries removed, got %d (len %d)", n, lru.Len())
+	}
+	if _, ok := lru.Peek("key2"); !ok {
+		t.Fatal("permanent key2 should remain")
+	}
+}
@@ -0,0 +1,30 @@
+package lru
+
+import (
+	"container/list"
+	"unsafe"
+)
+
+// 估算缓存簿记结构占用内存时使用的假设（64位平台）：
+//   - 每个条目有一个链表节点 list.Element 和一个条目结构体，按结构体大小计算，忽略分配器的尺寸取整
+//   - map 的每个槽位保存字符串头和指针，按装载因子 7/8 摊销空槽，另加 1 字节控制信息
+//   - 键和值本身的字节数不计入，它们已由 Bytes 统计
+const mapSlotOverhead = (int64(unsafe.Sizeof("")+unsafe.Sizeof((*list.Element)(nil))))*8/7 + 1
+
+// OverheadEstimate 估算缓存除键和值之外的簿记开销（字节）：链表节点、条目结构体和 map 槽位
+// 结果只是近似值，用于解释实际占用内存为何明显大于 Bytes
+func (c *Cache[V]) OverheadEstimate() int64 {
+	perEntry := int64(unsafe.Sizeof(list.Element{})+unsafe.Sizeof(entry[V]{})) + mapSlotOverhead
+	return int64(c.Len()) * perEntry
+}
+
+// OverheadEstimate 估算 ARC 除值之外的簿记开销（字节）
+// 包括 T1、T2 中的条目和 B1、B2 历史记录的链表节点与条目结构体，以及 map 槽位。
+// 历史记录不在缓存中，但它们的簿记结构同样占用内存
+func (arc *ARC) OverheadEstimate() int64 {
+	arc.mu.RLock()
+	defer arc.mu.RUnlock()
+	perNode := int64(unsafe.Sizeof(list.Element{}) + unsafe.Sizeof(arcEntry{}))
+	nodes := int64(arc.t1.Len() + arc.t2.Len() + arc.b1.Len() + arc.b2.Len())
+	return nodes*perNode + int64(len(arc.cache)+len(arc.ghosts))*mapSlotOverhead
+}
@@ -0,0 +1,129 @@
+package lru
+
+import "container/list"
+
+// Policy 是按字节统计内存的淘汰策略的公共接口，值类型为Value的Cache、LFU、SLRU以及ARCPolicy都实现了它，
+// 使用方可以在不关心具体淘汰算法的情况下替换缓存。与各实现一样不是并发安全的
+type Policy interface {
+	Add(key string, value Value)            // 添加或更新缓存项，必要时淘汰其他缓存项
+	Get(key string) (value Value, ok bool)  // 查找缓存项，命中计为一次访问
+	Peek(key string) (value Value, ok bool) // 查找缓存项，不计为访问
+	Delete(key string) bool                 // 删除缓存项，返回该键是否存在
+	RemoveOldest()                          // 淘汰一个最先被淘汰的缓存项
+	Keys() []string                         // 所有的键，大致按从最后被淘汰到最先被淘汰排列
+	Clear()                                 // 清空缓存
+	Len() int                               // 缓存项的数量
+	Bytes() int64                           // 键和值占用的内存（字节）
+
+	// SetOnEvicted 设置缓存项离开缓存（淘汰、Delete、Clear）时的回调，覆盖已有的键时不调用
+	SetOnEvicted(f func(key string, value Value))
+}
+
+var (
+	_ Policy = (*Cache[Value])(nil)
+	_ Policy = (*LFU)(nil)
+	_ Policy = (*SLRU)(nil)
+	_ Policy = (*ARCPolicy)(nil)
+)
+
+// ARCPolicy 将ARC适配为Policy，值必须实现Value接口
+// ARC按条目数限制容量，ARCPolicy只额外统计键和值占用的字节数，不按字节淘汰。
+// 不支持TTL，也不启动清理协程
+type ARCPolicy struct {
+	arc       *ARC
+	nbytes    int64
+	onEvicted func(key string, value Value)
+}
+
+// NewARCPolicy 创建最多保存capacity个缓存项的ARCPolicy
+func NewARCPolicy(capacity int) *ARCPolicy {
+	p := &ARCPolicy{arc: newARC(capacity)}
+	p.arc.OnEvicted = func(key string, value any) {
+		v := value.(Value)
+		p.nbytes -= int64(len(key)) + int64(v.Len())
+		if p.onEvicted != nil {
+			p.onEvicted(key, v)
+		}
+	}
+	return p
+}
+
+// ARC 返回底层的ARC，用于查看统计和内部状态
+func (p *ARCPolicy) ARC() *ARC {
+	return p.arc
+}
+
+// Add 添加或更新缓存项，ARC已满时按自适应替换淘汰一个缓存项
+func (p *ARCPolicy) Add(key string, value Value) {
+	if old, ok := p.arc.Peek(key); ok {
+		p.nbytes -= int64(len(key)) + int64(old.(Value).Len())
+	}
+	p.arc.Put(key, value)
+	if _, ok := p.arc.Peek(key); ok {
+		p.nbytes += int64(len(key)) + int64(value.Len())
+	}
+}
+
+// Get 查找缓存项，命中计为一次访问
+func (p *ARCPolicy) Get(key string) (value Value, ok bool) {
+	if v, ok := p.arc.Get(key); ok {
+		return v.(Value), true
+	}
+	return
+}
+
+// Peek 查找缓存项，不计为访问
+func (p *ARCPolicy) Peek(key string) (value Value, ok bool) {
+	if v, ok := p.arc.Peek(key); ok {
+		return v.(Value), true
+	}
+	return
+}
+
+// Delete 删除缓存项，返回该键是否存在
+func (p *ARCPolicy) Delete(key string) bool {
+	if _, ok := p.arc.Peek(key); !ok {
+		return false
+	}
+	p.arc.Remove(key)
+	return true
+}
+
+// RemoveOldest 按自适应替换的规则淘汰一个缓存项，被淘汰的键进入历史记录
+func (p *ARCPolicy) RemoveOldest() {
+	p.arc.RemoveOldest()
+}
+
+// Keys 返回所有的键，先T2后T1，各自从最近使用到最久未使用排列
+func (p *ARCPolicy) Keys() []string {
+	p.arc.mu.RLock()
+	defer p.arc.mu.RUnlock()
+	keys := make([]string, 0, p.arc.t1.Len()+p.arc.t2.Len())
+	for _, l := range []*list.List{p.arc.t2, p.arc.t1} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			keys = append(keys, e.Value.(*arcEntry).key)
+		}
+	}
+	return keys
+}
+
+// Clear 清空缓存
+func (p *ARCPolicy) Clear() {
+	p.arc.Clear()
+	p.nbytes = 0
+}
+
+// Len 返回缓存项的数量
+func (p *ARCPolicy) Len() int {
+	return p.arc.Size()
+}
+
+// Bytes 返回键和值占用的内存（字节）
+func (p *ARCPolicy) Bytes() int64 {
+	return p.nbytes
+}
+
+// SetOnEvicted 设置缓存项离开缓存时的回调
+func (p *ARCPolicy) SetOnEvicted(f func(key string, value Value)) {
+	p.onEvicted = f
+}
@@ -0,0 +1,177 @@
+package lru
+
+import "container/list"
+
+// defaultProtectedRatio 是受保护段默认占总内存的比例
+const defaultProtectedRatio = 0.8
+
+// SLRU 是分段LRU缓存，接口与Cache相同。注意：它不是并发安全的。
+//
+// 缓存分为试用段和受保护段：新写入的缓存项进入试用段，在试用段中再次被访问后提升到受保护段；
+// 淘汰总是先从试用段的末尾开始。受保护段超过自己的配额时，其中最久未使用的缓存项降级回试用段，
+// 而不是直接被淘汰。一次性扫描大量冷key只会在试用段中互相淘汰，不会挤掉受保护段中的热点key
+type SLRU struct {
+	maxBytes       int64                         // 缓存的最大内存占用（字节），0表示不限制
+	maxProtected   int64                         // 受保护段的最大内存占用（字节）
+	nbytes         int64                         // 两个段合计已使用的内存（字节）
+	protectedBytes int64                         // 受保护段已使用的内存（字节）
+	probation      *list.List                    // 试用段，最近使用的在前端
+	protected      *list.List                    // 受保护段，最近使用的在前端
+	cache          map[string]*list.Element      // 键到两个段中链表节点的映射
+	OnEvicted      func(key string, value Value) // 可选的回调函数，当缓存项被清除时调用
+}
+
+// slruEntry 是存储在SLRU链表中的缓存项
+type slruEntry struct {
+	key       string
+	value     Value
+	protected bool // 是否位于受保护段
+}
+
+// NewSLRU 是SLRU的构造函数，maxBytes为0表示不限制内存
+// protectedRatio 是受保护段占maxBytes的比例，不在(0, 1)之间时使用默认值0.8
+func NewSLRU(maxBytes int64, protectedRatio float64, onEvicted func(string, Value)) *SLRU {
+	if protectedRatio <= 0 || protectedRatio >= 1 {
+		protectedRatio = defaultProtectedRatio
+	}
+	return &SLRU{
+		maxBytes:     maxBytes,
+		maxProtected: int64(float64(maxBytes) * protectedRatio),
+		probation:    list.New(),
+		protected:    list.New(),
+		cache:        make(map[string]*list.Element),
+		OnEvicted:    onEvicted,
+	}
+}
+
+// Add 向缓存中添加一个值，新键进入试用段，更新已存在的键同样计为一次访问
+func (c *SLRU) Add(key string, value Value) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*slruEntry)
+		delta := int64(value.Len()) - int64(kv.value.Len())
+		c.nbytes += delta
+		if kv.protected {
+			c.protectedBytes += delta
+		}
+		kv.value = value
+		c.touch(ele)
+	} else {
+		c.cache[key] = c.probation.PushFront(&slruEntry{key: key, value: value})
+		c.nbytes += int64(len(key)) + int64(value.Len())
+	}
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes && len(c.cache) > 0 {
+		c.RemoveOldest()
+	}
+}
+
+// Get 查找键对应的值，试用段中的缓存项命中后提升到受保护段
+func (c *SLRU) Get(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		c.touch(ele)
+		return ele.Value.(*slruEntry).value, true
+	}
+	return
+}
+
+// Peek 查找键对应的值，不提升也不改变访问顺序
+func (c *SLRU) Peek(key string) (value Value, ok bool) {
+	if ele, ok := c.cache[key]; ok {
+		return ele.Value.(*slruEntry).value, true
+	}
+	return
+}
+
+// Keys 返回所有的键，先受保护段后试用段，各自从最近使用到最久未使用排列，即最先被淘汰的在最后
+func (c *SLRU) Keys() []string {
+	keys := make([]string, 0, len(c.cache))
+	for _, l := range []*list.List{c.protected, c.probation} {
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			keys = append(keys, ele.Value.(*slruEntry).key)
+		}
+	}
+	return keys
+}
+
+// SetOnEvicted 设置OnEvicted，用于通过Policy接口设置回调
+func (c *SLRU) SetOnEvicted(f func(key string, value Value)) {
+	c.OnEvicted = f
+}
+
+// touch 记录一次访问：受保护段中的缓存项移到前端，试用段中的缓存项提升到受保护段，
+// 受保护段因此（或因更新的值变大）超过配额时把其中最久未使用的缓存项降级到试用段的前端
+func (c *SLRU) touch(ele *list.Element) {
+	kv := ele.Value.(*slruEntry)
+	if kv.protected {
+		c.protected.MoveToFront(ele)
+	} else {
+		c.probation.Remove(ele)
+		kv.protected = true
+		c.cache[kv.key] = c.protected.PushFront(kv)
+		c.protectedBytes += int64(len(kv.key)) + int64(kv.value.Len())
+	}
+
+	for c.maxBytes != 0 && c.protectedBytes > c.maxProtected && c.protected.Len() > 1 {
+		last := c.protected.Back()
+		demoted := last.Value.(*slruEntry)
+		c.protected.Remove(last)
+		demoted.protected = false
+		c.cache[demoted.key] = c.probation.PushFront(demoted)
+		c.protectedBytes -= int64(len(demoted.key)) + int64(demoted.value.Len())
+	}
+}
+
+// RemoveOldest 淘汰试用段中最久未使用的缓存项，试用段为空时淘汰受保护段中最久未使用的
+// 方法名与Cache保持一致，便于两者互相替换
+func (c *SLRU) RemoveOldest() {
+	ele := c.probation.Back()
+	if ele == nil {
+		ele = c.protected.Back()
+	}
+	if ele != nil {
+		c.removeElement(ele)
+	}
+}
+
+// Delete 删除指定键的缓存项，返回该键是否存在，删除时同样会调用OnEvicted
+func (c *SLRU) Delete(key string) bool {
+	ele, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	c.removeElement(ele)
+	return true
+}
+
+// Clear 清空缓存，按淘汰顺序对每个被丢弃的缓存项调用OnEvicted
+func (c *SLRU) Clear() {
+	for len(c.cache) > 0 {
+		c.RemoveOldest()
+	}
+}
+
+// removeElement 从所在的段中删除节点，更新内存占用并调用OnEvicted
+func (c *SLRU) removeElement(ele *list.Element) {
+	kv := ele.Value.(*slruEntry)
+	size := int64(len(kv.key)) + int64(kv.value.Len())
+	if kv.protected {
+		c.protected.Remove(ele)
+		c.protectedBytes -= size
+	} else {
+		c.probation.Remove(ele)
+	}
+	delete(c.cache, kv.key)
+	c.nbytes -= size
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+}
+
+// Bytes 返回两个段合计占用的内存（字节），包括键和值
+func (c *SLRU) Bytes() int64 {
+	return c.nbytes
+}
+
+// Len 返回两个段中的元素总数
+func (c *SLRU) Len() int {
+	return len(c.cache)
+}
@@ -0,0 +1,66 @@
+package lru
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSLRUScanResistance(t *testing.T) {
+	// 每个热点项3字节，每个冷key 4字节；受保护段最多15字节，能容纳全部4个热点项
+	var evicted []string
+	slru := NewSLRU(30, 0.5, func(key string, value Value) { evicted = append(evicted, key) })
+	hot := []string{"h0", "h1", "h2", "h3"}
+	for _, key := range hot {
+		slru.Add(key, String("v"))
+		slru.Get(key) // 再次访问，提升到受保护段
+	}
+	// 一次性扫描大量冷key，它们只在试用段中互相淘汰
+	for i := 0; i < 100; i++ {
+		slru.Add(fmt.Sprintf("c%02d", i), String("v"))
+	}
+
+	for _, key := range hot {
+		if _, ok := slru.Get(key); !ok {
+			t.Fatalf("SLRU should keep the protected key %s after a scan", key)
+		}
+	}
+	for _, key := range evicted {
+		if key[0] != 'c' {
+			t.Fatalf("only cold keys should be evicted, got %s", key)
+		}
+	}
+	// 试用段剩余的空间留给最后写入的冷key
+	const probation = (30 - 12) / 4
+	if len(evicted) != 100-probation {
+		t.Fatalf("expect %d cold keys evicted, got %d", 100-probation, len(evicted))
+	}
+	if _, ok := slru.Get(fmt.Sprintf("c%02d", 99)); !ok {
+		t.Fatal("the newest cold key should stay in the probation segment")
+	}
+	if slru.Bytes() > 30 || slru.Len() != 4+probation {
+		t.Fatalf("unexpected accounting: %d bytes, %d entries", slru.Bytes(), slru.Len())
+	}
+}
+
+func TestSLRUDemotion(t *testing.T) {
+	var evicted []string
+	// 受保护段最多6字节，即两个缓存项
+	slru := NewSLRU(12, 0.5, func(key string, value Value) { evicted = append(evicted, key) })
+	for _, key := range []string{"k1", "k2", "k3"} {
+		slru.Add(key, String("v"))
+		slru.Get(key)
+	}
+	// k1 因受保护段超过配额被降级回试用段，下一次淘汰时首先被移除
+	if slru.protectedBytes != 6 {
+		t.Fatalf("expect protected segment at its 6-byte quota, got %d", slru.protectedBytes)
+	}
+	slru.Add("k4", String("v"))
+	slru.Add("k5", String("v"))
+	if len(evicted) != 1 || evicted[0] != "k1" {
+		t.Fatalf("expect demoted k1 to be evicted first, got %v", evicted)
+	}
+	slru.Add("k2", String("vvvv")) // 更新受保护段中的值
+	if slru.Bytes() > 12 {
+		t.Fatalf("expect bytes within limit after growing a protected value, got %d", slru.Bytes())
+	}
+}
@@ -0,0 +1,51 @@
+package lru
+
+import "sync"
+
+// SyncCache 是并发安全的Cache，所有方法内部加锁
+// Get会调整访问顺序并更新统计，因此与Add一样需要写锁，只有Len使用读锁。
+// OnEvicted在持有锁时调用，回调中不能再调用该SyncCache的方法
+type SyncCache struct {
+	mu sync.RWMutex
+	c  *Cache[Value]
+}
+
+// NewSync 创建并发安全的Cache，参数与New相同
+func NewSync(maxBytes int64, onEvicted func(string, Value)) *SyncCache {
+	return &SyncCache{c: New(maxBytes, onEvicted)}
+}
+
+// Add 向缓存中添加一个值
+func (s *SyncCache) Add(key string, value Value) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Add(key, value)
+}
+
+// Get 查找键对应的值
+func (s *SyncCache) Get(key string) (value Value, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Get(key)
+}
+
+// Delete 删除指定键的缓存项，返回该键是否存在
+func (s *SyncCache) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Delete(key)
+}
+
+// Len 返回缓存中的元素个数
+func (s *SyncCache) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.c.Len()
+}
+
+// Resize 修改最大内存限制并淘汰超出的缓存项，返回被淘汰的缓存项数量
+func (s *SyncCache) Resize(maxBytes int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Resize(maxBytes)
+}
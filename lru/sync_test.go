@@ -0,0 +1,45 @@
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestSyncCacheConcurrent 需要配合 go test -race 运行才能发现数据竞争
+func TestSyncCacheConcurrent(t *testing.T) {
+	c := NewSync(1<<10, nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("key%d", (g*31+i)%64)
+				switch i % 5 {
+				case 0:
+					c.Delete(key)
+				case 1:
+					c.Len()
+				case 2:
+					if i%50 == 2 {
+						c.Resize(int64(512 + g*64))
+					}
+				default:
+					c.Add(key, String("value"))
+					c.Get(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	c.Resize(0)
+	c.Add("final", String("v"))
+	if v, ok := c.Get("final"); !ok || string(v.(String)) != "v" {
+		t.Fatalf("expect final=v after concurrent use, got %v", v)
+	}
+	if !c.Delete("final") || c.Delete("final") {
+		t.Fatal("Delete should report presence exactly once")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	gocachex "goCacheX/cache"
 	"log"
 	"net/http"
+	"time"
 )
 
 var db = map[string]string{
@@ -42,18 +43,7 @@ func startCacheServer(addr string, addrs []string, gee *gocachex.Group) {
 }
 
 func startAPIServer(apiAddr string, gee *gocachex.Group) {
-	http.Handle("/api", http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			key := r.URL.Query().Get("key")
-			view, err := gee.Get(key)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.Header().Set("Content-Type", "application/octet-stream")
-			w.Write(view.ByteSlice())
-
-		}))
+	http.Handle("/api", gocachex.APIHandler(gee, time.Minute))
 	log.Println("fontend server is running at", apiAddr)
 	log.Fatal(http.ListenAndServe(apiAddr[7:], nil))
 
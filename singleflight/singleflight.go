@@ -3,17 +3,45 @@ package singleflight
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 type call struct {
 	wg  sync.WaitGroup
 	val any
 	err error
+
+	expireAt time.Time // 结果保留的截止时间，零值表示调用仍在进行中
+}
+
+// Clock 提供当前时间，用于判断保留的结果是否过期
+type Clock interface {
+	Now() time.Time
 }
 
 type Group struct {
 	mu sync.Mutex
 	m  map[string]*call
+
+	// Hold 是调用成功后结果的保留时长
+	// 在此窗口内到达的相同key请求直接复用上一次的结果，不再执行fn，
+	// 用于平滑突发的重复请求；为0时调用完成后立即删除（默认行为）。
+	// 返回错误的调用不会保留：错误可能是临时的或只属于发起调用的请求（例如ctx被取消），
+	// 不能交给之后到达的调用方
+	Hold time.Duration
+
+	// Clock 是判断保留窗口时使用的时间源，为nil时使用真实时间
+	// 测试可以注入可控的时钟，无需等待即可验证窗口边界两侧的行为。
+	// 过期结果的清理仍由真实时间的定时器完成，只影响内存回收的时机
+	Clock Clock
+}
+
+// now 返回时间源的当前时间
+func (g *Group) now() time.Time {
+	if g.Clock == nil {
+		return time.Now()
+	}
+	return g.Clock.Now()
 }
 
 func (g *Group) Do(key string, fn func() (any, error)) (any, error) {
@@ -26,7 +54,7 @@ func (g *Group) Do(key string, fn func() (any, error)) (any, error) {
 		g.mu.Unlock()
 		return nil, fmt.Errorf("key is empty")
 	}
-	if c, ok := g.m[key]; ok {
+	if c, ok := g.m[key]; ok && (c.expireAt.IsZero() || g.now().Before(c.expireAt)) {
 		g.mu.Unlock()
 		c.wg.Wait()
 		return c.val, c.err
@@ -44,10 +72,53 @@ func (g *Group) Do(key string, fn func() (any, error)) (any, error) {
 	}()
 
 	c.wg.Wait() // 等待后台函数完成
+	g.finish(key, c)
+	return c.val, c.err
+}
 
+// Begin 登记一个由调用方在外部完成的调用，之后到达的相同key的Do不执行fn，而是等待done并得到传给它的结果
+// key为空，或已有进行中（以及保留窗口内）的调用时返回ok=false，此时没有登记任何调用。
+// 登记成功后调用方必须且只能调用一次done，否则等待的Do会一直阻塞
+func (g *Group) Begin(key string) (done func(val any, err error), ok bool) {
 	g.mu.Lock()
-	delete(g.m, key)
-	g.mu.Unlock()
+	defer g.mu.Unlock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if key == "" {
+		return nil, false
+	}
+	if c, ok := g.m[key]; ok && (c.expireAt.IsZero() || g.now().Before(c.expireAt)) {
+		return nil, false
+	}
+	c := new(call)
+	c.wg.Add(1)
+	g.m[key] = c
+	return func(val any, err error) {
+		c.val, c.err = val, err
+		c.wg.Done()
+		g.finish(key, c)
+	}, true
+}
 
-	return c.val, c.err
+// finish 在调用完成后按Hold保留结果或删除调用
+func (g *Group) finish(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Hold > 0 && c.err == nil {
+		// 保留结果一段时间，过期后由定时器清理
+		c.expireAt = g.now().Add(g.Hold)
+		time.AfterFunc(g.Hold, func() { g.forget(key, c) })
+	} else if g.m[key] == c {
+		delete(g.m, key)
+	}
+}
+
+// forget 在key仍对应c时将其删除，避免误删保留期过后新发起的调用
+func (g *Group) forget(key string, c *call) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
 }
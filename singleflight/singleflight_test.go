@@ -1,8 +1,10 @@
 package singleflight
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -157,3 +159,156 @@ func TestDoEmptyKey(t *testing.T) {
 		t.Error("期望空key返回错误，但未返回")
 	}
 }
+
+// 测试结果保留窗口内的错峰请求复用同一次结果
+func TestDoHold(t *testing.T) {
+	run := func(hold time.Duration) int32 {
+		g := &Group{Hold: hold}
+		var calls int32
+		fn := func() (any, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(5 * time.Millisecond)
+			return "value", nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(delay time.Duration) {
+				defer wg.Done()
+				time.Sleep(delay)
+				if v, err := g.Do("key", fn); err != nil || v != "value" {
+					t.Errorf("期望value，得到%v，错误%v", v, err)
+				}
+			}(time.Duration(i) * 20 * time.Millisecond)
+		}
+		wg.Wait()
+		return atomic.LoadInt32(&calls)
+	}
+
+	if calls := run(0); calls != 5 {
+		t.Errorf("未开启保留窗口时期望执行5次，得到%d", calls)
+	}
+	if calls := run(time.Second); calls != 1 {
+		t.Errorf("开启保留窗口时期望执行1次，得到%d", calls)
+	}
+}
+
+// 测试保留窗口过期后重新执行fn
+func TestDoHoldExpire(t *testing.T) {
+	g := &Group{Hold: 20 * time.Millisecond}
+	calls := 0
+	fn := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+
+	g.Do("key", fn)
+	g.Do("key", fn)
+	if calls != 1 {
+		t.Fatalf("保留窗口内期望执行1次，得到%d", calls)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if v, _ := g.Do("key", fn); v != 2 {
+		t.Fatalf("保留窗口过期后期望重新执行，得到%v", v)
+	}
+}
+
+// fakeClock 是只在测试中手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// 测试注入时钟后保留窗口边界两侧的行为，不依赖真实时间
+func TestDoHoldClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	g := &Group{Hold: time.Minute, Clock: clock}
+	calls := 0
+	fn := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+
+	g.Do("key", fn)
+	clock.Advance(time.Minute - time.Nanosecond)
+	if v, _ := g.Do("key", fn); v != 1 || calls != 1 {
+		t.Fatalf("窗口内应复用结果，得到 %v，执行次数 %d", v, calls)
+	}
+
+	clock.Advance(time.Nanosecond)
+	if v, _ := g.Do("key", fn); v != 2 || calls != 2 {
+		t.Fatalf("窗口结束后应重新执行，得到 %v，执行次数 %d", v, calls)
+	}
+}
+
+// 测试保留窗口不保留错误，窗口内到达的请求重新执行fn
+func TestDoHoldError(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	g := &Group{Hold: time.Minute, Clock: clock}
+	calls := 0
+	fn := func() (any, error) {
+		calls++
+		if calls == 1 {
+			return nil, context.Canceled
+		}
+		return "value", nil
+	}
+
+	if _, err := g.Do("key", fn); err != context.Canceled {
+		t.Fatalf("期望第一次调用返回context.Canceled，得到%v", err)
+	}
+	clock.Advance(time.Second)
+	if v, err := g.Do("key", fn); err != nil || v != "value" {
+		t.Fatalf("错误不应在保留窗口内复用，得到%v，错误%v", v, err)
+	}
+	if v, _ := g.Do("key", fn); v != "value" || calls != 2 {
+		t.Fatalf("成功的结果应被保留，执行%d次", calls)
+	}
+}
+
+// 测试Begin登记的调用完成前，相同key的Do等待其结果而不执行fn
+func TestBegin(t *testing.T) {
+	g := new(Group)
+	done, ok := g.Begin("k")
+	if !ok {
+		t.Fatal("Begin should register the call")
+	}
+	if _, ok := g.Begin("k"); ok {
+		t.Fatal("Begin should fail while a call is in flight")
+	}
+
+	var calls atomic.Int32
+	result := make(chan any)
+	go func() {
+		v, _ := g.Do("k", func() (any, error) {
+			calls.Add(1)
+			return "loaded", nil
+		})
+		result <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+	done("filled", nil)
+	if v := <-result; v != "filled" || calls.Load() != 0 {
+		t.Fatalf("Do should wait for the begun call, got %v with %d calls", v, calls.Load())
+	}
+
+	// 完成后key可以重新登记
+	done, ok = g.Begin("k")
+	if !ok {
+		t.Fatal("Begin should succeed after the call finished")
+	}
+	done(nil, nil)
+}